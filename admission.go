@@ -0,0 +1,129 @@
+package largecache
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// AdmissionPolicy decides whether a new key may evict an entry from a full shard.
+type AdmissionPolicy int
+
+const (
+	// AdmitAll stores every new key, evicting as many entries as it needs
+	AdmitAll AdmissionPolicy = iota
+	// TinyLFU stores a new key in a full shard only when it was requested more often than the oldest entry.
+	// Request frequencies are estimated with a count-min sketch which halves its counters as it ages.
+	TinyLFU
+)
+
+const (
+	// sketchDepth is the number of counter rows, a key is counted once in each of them
+	sketchDepth = 4
+	// maxSketchCount saturates the counters, as in 4 bit counters
+	maxSketchCount = 15
+	// sketchSampleFactor times the row width increments age the sketch
+	sketchSampleFactor = 10
+)
+
+var sketchSeeds = [sketchDepth]uint64{0xc3a5c85c97cb3127, 0xb492b66fbe98f273, 0x9ae16a3b2f90404f, 0xcbf29ce484222325}
+
+// frequencySketch estimates how often hashed keys were requested recently.
+// Reads record requests under the shard read lock, so the sketch has its own lock.
+type frequencySketch struct {
+	lock       sync.Mutex
+	counters   []uint8
+	mask       uint64
+	additions  int
+	sampleSize int
+}
+
+func newFrequencySketch(policy AdmissionPolicy, entries int) *frequencySketch {
+	if policy == AdmitAll {
+		return nil
+	}
+	width := 16
+	for width < entries {
+		width *= 2
+	}
+	return &frequencySketch{
+		counters:   make([]uint8, sketchDepth*width),
+		mask:       uint64(width - 1),
+		sampleSize: sketchSampleFactor * width,
+	}
+}
+
+func (f *frequencySketch) index(hashedKey uint64, row int) int {
+	h := (hashedKey ^ sketchSeeds[row]) * 0x9e3779b97f4a7c15
+	return row*int(f.mask+1) + int((h>>32)&f.mask)
+}
+
+func (f *frequencySketch) increment(hashedKey uint64) {
+	f.lock.Lock()
+	added := false
+	for row := 0; row < sketchDepth; row++ {
+		if i := f.index(hashedKey, row); f.counters[i] < maxSketchCount {
+			f.counters[i]++
+			added = true
+		}
+	}
+	if added {
+		f.additions++
+		if f.additions >= f.sampleSize {
+			f.ageWithoutLock()
+		}
+	}
+	f.lock.Unlock()
+}
+
+func (f *frequencySketch) estimate(hashedKey uint64) uint8 {
+	f.lock.Lock()
+	count := uint8(maxSketchCount)
+	for row := 0; row < sketchDepth; row++ {
+		if c := f.counters[f.index(hashedKey, row)]; c < count {
+			count = c
+		}
+	}
+	f.lock.Unlock()
+	return count
+}
+
+// ageWithoutLock halves every counter, so keys which are no longer requested lose their weight.
+func (f *frequencySketch) ageWithoutLock() {
+	for i := range f.counters {
+		f.counters[i] /= 2
+	}
+	f.additions /= 2
+}
+
+func (f *frequencySketch) reset() {
+	f.lock.Lock()
+	clear(f.counters)
+	f.additions = 0
+	f.lock.Unlock()
+}
+
+// recordRequest counts a read or write of the key in the admission sketch.
+func (s *cacheShard) recordRequest(hashedKey uint64) {
+	if s.admission != nil {
+		s.admission.increment(hashedKey)
+	}
+}
+
+// admitWithoutLock tells whether a new key may evict the oldest entry of the full shard.
+// Deleted and expired entries are free to evict.
+func (s *cacheShard) admitWithoutLock(hashedKey uint64, currentTimestamp uint64) bool {
+	oldest, err := s.entries.Peek()
+	if err != nil {
+		return true
+	}
+	victim := readHashFromEntry(oldest)
+	if victim == 0 || s.isExpired(oldest, currentTimestamp) {
+		return true
+	}
+	if s.admission.estimate(hashedKey) > s.admission.estimate(victim) {
+		atomic.AddInt64(&s.stats.Admitted, 1)
+		return true
+	}
+	atomic.AddInt64(&s.stats.Rejected, 1)
+	return false
+}
@@ -0,0 +1,84 @@
+package largecache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTinyLFURejectsOneHitWonder(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := New(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         100 * time.Second,
+		MaxEntriesInWindow: 100,
+		MaxEntriesSize:     256,
+		HardMaxCacheSize:   1,
+		AdmissionPolicy:    TinyLFU,
+	})
+	defer cache.Close()
+	value := blob('a', 1024*300)
+	for _, key := range []string{"a", "b", "c"} {
+		noError(t, cache.Set(key, value))
+		cache.Get(key)
+	}
+
+	// when
+	noError(t, cache.Set("crawled", value))
+
+	// then
+	_, err := cache.Get("crawled")
+	assertEqual(t, ErrEntryNotFound, err)
+	_, err = cache.Get("a")
+	noError(t, err)
+	assertEqual(t, int64(0), cache.Stats().Admitted)
+	assertEqual(t, int64(1), cache.Stats().Rejected)
+
+	// when
+	for i := 0; i < 5; i++ {
+		cache.Get("hot")
+	}
+	noError(t, cache.Set("hot", value))
+
+	// then
+	_, err = cache.Get("hot")
+	noError(t, err)
+	_, err = cache.Get("a")
+	assertEqual(t, ErrEntryNotFound, err)
+	assertEqual(t, int64(1), cache.Stats().Admitted)
+}
+
+func TestFrequencySketchAges(t *testing.T) {
+	t.Parallel()
+
+	// given
+	sketch := newFrequencySketch(TinyLFU, 16)
+	for i := 0; i < 10; i++ {
+		sketch.increment(42)
+	}
+
+	// when
+	for hashedKey := uint64(1000); hashedKey < uint64(1000+sketch.sampleSize); hashedKey++ {
+		sketch.increment(hashedKey)
+	}
+
+	// then
+	assertEqual(t, true, sketch.estimate(42) < 10)
+	assertEqual(t, true, sketch.estimate(42) > 0)
+}
+
+func TestInvalidAdmissionPolicy(t *testing.T) {
+	t.Parallel()
+
+	// given
+	config := DefaultConf(5 * time.Second)
+	config.AdmissionPolicy = TinyLFU + 1
+
+	// when
+	_, err := New(context.Background(), config)
+
+	// then
+	assertEqual(t, "AdmissionPolicy must be AdmitAll or TinyLFU", err.Error())
+}
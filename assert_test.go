@@ -10,7 +10,7 @@ import (
 )
 
 func assertEqual(t *testing.T, expected, actual interface{}, msgAndArgs ...interface{}) {
-	if objectAreEqual(expected, actual) {
+	if !objectAreEqual(expected, actual) {
 		_, file, line, _ := runtime.Caller(1)
 		file = path.Base(file)
 		t.Errorf(fmt.Sprintf("\n%s:%d: Not equal: \n"+
@@ -0,0 +1,162 @@
+package largecache
+
+import "sync/atomic"
+
+// GetMany reads entries for the keys taking every involved shard lock once.
+// Entries and errors are returned in the order of keys.
+func (c *LargeCache) GetMany(keys []string) ([][]byte, []error) {
+	entries := make([][]byte, len(keys))
+	errs := make([]error, len(keys))
+	hashes := c.hashKeys(keys)
+
+	for shardIndex, indexes := range c.groupByShard(hashes) {
+		c.shards[shardIndex].getMany(keys, hashes, indexes, entries, errs)
+	}
+	return entries, errs
+}
+
+// SetMany saves entries taking every involved shard lock once.
+// It returns errors of the keys which could not be saved.
+// With Config.Store the keys are saved one by one, each to the Store and then to its shard.
+func (c *LargeCache) SetMany(entries map[string][]byte) map[string]error {
+	var errs map[string]error
+	if c.store != nil {
+		for key, entry := range entries {
+			if err := c.Set(key, entry); err != nil {
+				if errs == nil {
+					errs = make(map[string]error)
+				}
+				errs[key] = err
+			}
+		}
+		return errs
+	}
+
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	hashes := c.hashKeys(keys)
+
+	for shardIndex, indexes := range c.groupByShard(hashes) {
+		shardErrs := c.shards[shardIndex].setMany(keys, hashes, indexes, entries)
+		for key, err := range shardErrs {
+			if errs == nil {
+				errs = make(map[string]error)
+			}
+			errs[key] = err
+		}
+	}
+	return errs
+}
+
+// DeleteMany removes the keys taking every involved shard lock once.
+// Errors are returned in the order of keys.
+// With Config.Store the keys are deleted one by one, each from the Store and then from its shard.
+func (c *LargeCache) DeleteMany(keys []string) []error {
+	errs := make([]error, len(keys))
+	if c.store != nil {
+		for i, key := range keys {
+			errs[i] = c.Delete(key)
+		}
+		return errs
+	}
+
+	hashes := c.hashKeys(keys)
+
+	for shardIndex, indexes := range c.groupByShard(hashes) {
+		c.shards[shardIndex].delMany(keys, hashes, indexes, errs)
+	}
+	return errs
+}
+
+func (c *LargeCache) hashKeys(keys []string) []uint64 {
+	hashes := make([]uint64, len(keys))
+	for i, key := range keys {
+		hashes[i] = c.hash.Sum64(key)
+	}
+	return hashes
+}
+
+// groupByShard maps shard index to the positions of hashes which belong to it.
+func (c *LargeCache) groupByShard(hashes []uint64) map[uint64][]int {
+	groups := make(map[uint64][]int)
+	for i, hashedKey := range hashes {
+		shardIndex := hashedKey & c.shardMask
+		groups[shardIndex] = append(groups[shardIndex], i)
+	}
+	return groups
+}
+
+func (s *cacheShard) getMany(keys []string, hashes []uint64, indexes []int, entries [][]byte, errs []error) {
+	currentTime := uint64(s.clock.Epoch())
+	hits := 0
+
+	s.lock.RLock()
+	for _, i := range indexes {
+		entries[i], errs[i] = s.getWithoutLock(defaultNamespace, keys[i], hashes[i], currentTime)
+		if errs[i] == nil {
+			hits++
+		}
+	}
+	s.lock.RUnlock()
+
+	if hits == 0 {
+		return
+	}
+	atomic.AddInt64(&s.stats.Hits, int64(hits))
+	if s.statsEnabled || s.accesses != nil || s.idleExpiration {
+		s.lock.Lock()
+		for _, i := range indexes {
+			if errs[i] != nil {
+				continue
+			}
+			s.recordAccessWithoutLock(hashes[i])
+			if s.idleExpiration {
+				s.touchWithoutLock(defaultNamespace, keys[i], hashes[i], currentTime)
+			}
+		}
+		s.lock.Unlock()
+	}
+}
+
+func (s *cacheShard) setMany(keys []string, hashes []uint64, indexes []int, entries map[string][]byte) map[string]error {
+	currentTimestamp := uint64(s.clock.Epoch())
+	var errs map[string]error
+
+	s.lock.Lock()
+	for _, i := range indexes {
+		if err := s.setWithoutLock(currentTimestamp, defaultNamespace, keys[i], hashes[i], entries[keys[i]], 0); err != nil {
+			if errs == nil {
+				errs = make(map[string]error)
+			}
+			errs[keys[i]] = err
+		}
+	}
+	s.lock.Unlock()
+
+	return errs
+}
+
+func (s *cacheShard) delMany(keys []string, hashes []uint64, indexes []int, errs []error) {
+	s.lock.Lock()
+	for _, i := range indexes {
+		itemIndex, _ := s.findWithoutLock(defaultNamespace, keys[i], hashes[i])
+		if itemIndex == 0 {
+			s.delmiss()
+			errs[i] = ErrEntryNotFound
+			continue
+		}
+
+		wrappedEntry, err := s.entries.Get(int(itemIndex))
+		if err != nil {
+			s.delmiss()
+			errs[i] = err
+			continue
+		}
+
+		s.removeWithoutLock(wrappedEntry, hashes[i], Deleted)
+		s.delhit()
+	}
+	s.lock.Unlock()
+}
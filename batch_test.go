@@ -0,0 +1,88 @@
+package largecache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestGetMany(t *testing.T) {
+	t.Parallel()
+
+	cache, _ := New(context.Background(), Config{
+		Shards:             8,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 100,
+		MaxEntriesSize:     256,
+		StatsEnabled:       true,
+	})
+	defer cache.Close()
+	for i := 0; i < 10; i++ {
+		cache.Set(fmt.Sprintf("key%d", i), []byte(fmt.Sprintf("value%d", i)))
+	}
+
+	keys := []string{"key3", "missing", "key7", "key0"}
+	entries, errs := cache.GetMany(keys)
+
+	assertEqual(t, []byte("value3"), entries[0])
+	assertEqual(t, []byte(nil), entries[1])
+	assertEqual(t, []byte("value7"), entries[2])
+	assertEqual(t, []byte("value0"), entries[3])
+	assertEqual(t, []error{nil, ErrEntryNotFound, nil, nil}, errs)
+	assertEqual(t, int64(3), cache.Stats().Hits)
+	assertEqual(t, int64(1), cache.Stats().Misses)
+	assertEqual(t, uint32(1), cache.keyMetadata("key7").RequestCount)
+}
+
+func TestSetMany(t *testing.T) {
+	t.Parallel()
+
+	cache, _ := New(context.Background(), Config{
+		Shards:             8,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 100,
+		MaxEntriesSize:     256,
+	})
+	defer cache.Close()
+
+	entries := make(map[string][]byte)
+	for i := 0; i < 100; i++ {
+		entries[fmt.Sprintf("key%d", i)] = []byte(fmt.Sprintf("value%d", i))
+	}
+
+	errs := cache.SetMany(entries)
+	assertEqual(t, 0, len(errs))
+	assertEqual(t, 100, cache.Len())
+
+	for key, entry := range entries {
+		value, err := cache.Get(key)
+		noError(t, err)
+		assertEqual(t, entry, value)
+	}
+}
+
+func TestDeleteMany(t *testing.T) {
+	t.Parallel()
+
+	var deleted []string
+	cache, _ := New(context.Background(), Config{
+		Shards:             8,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 100,
+		MaxEntriesSize:     256,
+		OnRemoveWithReason: func(key string, entry []byte, reason RemoveReason) {
+			assertEqual(t, Deleted, reason)
+			deleted = append(deleted, key)
+		},
+	})
+	defer cache.Close()
+	cache.Set("key1", []byte("value"))
+	cache.Set("key2", []byte("value"))
+
+	errs := cache.DeleteMany([]string{"key1", "missing", "key2"})
+
+	assertEqual(t, []error{nil, ErrEntryNotFound, nil}, errs)
+	assertEqual(t, 2, len(deleted))
+	assertEqual(t, 0, cache.Len())
+}
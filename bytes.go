@@ -1,3 +1,5 @@
+//go:build !appengine
+
 package largecache
 
 import "unsafe"
@@ -1,3 +1,5 @@
+//go:build appengine
+
 package largecache
 
 func bytesToString(b []byte) string {
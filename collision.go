@@ -0,0 +1,169 @@
+package largecache
+
+// collisionKey identifies an entry kept in collisions because an entry of another key
+// holds its hash in hashmap.
+type collisionKey struct {
+	namespace uint32
+	key       string
+}
+
+func entryCollisionKey(wrappedEntry []byte) collisionKey {
+	return collisionKey{namespace: readNamespaceFromEntry(wrappedEntry), key: readKeyFromEntry(wrappedEntry)}
+}
+
+// findWithoutLock returns the index of the entry stored for the key of the namespace, 0 when there is none,
+// and whether it is kept in collisions.
+func (s *cacheShard) findWithoutLock(namespace uint32, key string, hashedKey uint64) (index uint64, collided bool) {
+	if len(s.collisions) > 0 {
+		if index, ok := s.collisions[collisionKey{namespace: namespace, key: key}]; ok {
+			return index, true
+		}
+	}
+	index = s.hashmap[hashedKey]
+	if index == 0 {
+		return 0, false
+	}
+	wrappedEntry, err := s.entries.Get(int(index))
+	if err != nil || !compareKeyFromEntry(wrappedEntry, key) || readNamespaceFromEntry(wrappedEntry) != namespace {
+		return 0, false
+	}
+	return index, false
+}
+
+// getWrappedEntryByKey returns the entry stored for the key of the namespace, unlike getWrappedEntry
+// it never returns the entry of another key with the same hash.
+func (s *cacheShard) getWrappedEntryByKey(namespace uint32, key string, hashedKey uint64) ([]byte, error) {
+	index, _ := s.findWithoutLock(namespace, key, hashedKey)
+	if index == 0 {
+		s.miss()
+		return nil, ErrEntryNotFound
+	}
+
+	wrappedEntry, err := s.entries.Get(int(index))
+	if err != nil {
+		s.miss()
+		return nil, err
+	}
+	return wrappedEntry, nil
+}
+
+// collidesWithoutLock tells whether a new entry for the key goes to collisions,
+// because hashmap holds an entry of another key under its hash.
+func (s *cacheShard) collidesWithoutLock(namespace uint32, key string, hashedKey uint64) bool {
+	if _, collided := s.findWithoutLock(namespace, key, hashedKey); collided {
+		return true
+	}
+	index := s.hashmap[hashedKey]
+	if index == 0 {
+		return false
+	}
+	wrappedEntry, err := s.entries.Get(int(index))
+	return err == nil && (!compareKeyFromEntry(wrappedEntry, key) || readNamespaceFromEntry(wrappedEntry) != namespace)
+}
+
+// indexWithoutLock records the index of an entry pushed for the key, isNew counts a genuine collision
+// when the key was not stored before.
+func (s *cacheShard) indexWithoutLock(wrappedEntry []byte, hashedKey uint64, index uint64, collides bool, isNew bool) {
+	if !collides {
+		s.hashmap[hashedKey] = index
+		return
+	}
+
+	ck := entryCollisionKey(wrappedEntry)
+	s.collisions[ck] = index
+	if isNew {
+		s.collision()
+		if s.isVerbose {
+			if primary, err := s.entries.Get(int(s.hashmap[hashedKey])); err == nil {
+				s.logger.Printf("Collision detected. Both %q and %q have the same hash %x", ck.key, readKeyFromEntry(primary), hashedKey)
+			}
+		}
+	}
+}
+
+// reindexWithoutLock points the index of an entry moved within the shard at its new position.
+func (s *cacheShard) reindexWithoutLock(wrappedEntry []byte, hashedKey uint64, index uint64) {
+	if len(s.collisions) > 0 {
+		ck := entryCollisionKey(wrappedEntry)
+		if _, ok := s.collisions[ck]; ok {
+			s.collisions[ck] = index
+			return
+		}
+	}
+	s.hashmap[hashedKey] = index
+}
+
+// unindexWithoutLock forgets the index of a stored entry, wherever it is kept.
+func (s *cacheShard) unindexWithoutLock(wrappedEntry []byte, hashedKey uint64) {
+	if len(s.collisions) > 0 {
+		ck := entryCollisionKey(wrappedEntry)
+		if _, ok := s.collisions[ck]; ok {
+			delete(s.collisions, ck)
+			return
+		}
+	}
+	delete(s.hashmap, hashedKey)
+}
+
+// isIndexedWithoutLock tells whether the entry is the one stored for its key.
+func (s *cacheShard) isIndexedWithoutLock(wrappedEntry []byte, hashedKey uint64) bool {
+	if len(s.collisions) > 0 {
+		if _, ok := s.collisions[entryCollisionKey(wrappedEntry)]; ok {
+			return true
+		}
+	}
+	_, ok := s.hashmap[hashedKey]
+	return ok
+}
+
+// forEachIndexWithoutLock calls fn with the hash and index of every stored entry, those kept
+// in collisions included. fn may remove the entry it is called with.
+func (s *cacheShard) forEachIndexWithoutLock(fn func(hashedKey uint64, index uint64)) {
+	for hashedKey, index := range s.hashmap {
+		fn(hashedKey, index)
+	}
+	for _, index := range s.collisions {
+		if wrappedEntry, err := s.entries.Get(int(index)); err == nil {
+			fn(readHashFromEntry(wrappedEntry), index)
+		}
+	}
+}
+
+// entryRef identifies a stored entry between two locks of the shard, by its hash when it is
+// kept in hashmap and by its key when it is kept in collisions.
+type entryRef struct {
+	hashedKey uint64
+	collided  bool
+	key       collisionKey
+}
+
+// copyEntryRefs returns references to every stored entry of the shard, those kept in collisions included.
+func (s *cacheShard) copyEntryRefs() (refs []entryRef, next int) {
+	s.lock.RLock()
+	refs = make([]entryRef, 0, len(s.hashmap)+len(s.collisions))
+	for hashedKey := range s.hashmap {
+		refs = append(refs, entryRef{hashedKey: hashedKey})
+	}
+	for ck, index := range s.collisions {
+		if wrappedEntry, err := s.entries.Get(int(index)); err == nil {
+			refs = append(refs, entryRef{hashedKey: readHashFromEntry(wrappedEntry), collided: true, key: ck})
+		}
+	}
+	s.lock.RUnlock()
+	return refs, len(refs)
+}
+
+// getEntryByRefWithoutLock returns the entry the reference points at, ErrEntryNotFound
+// when it was removed since the reference was taken.
+func (s *cacheShard) getEntryByRefWithoutLock(ref entryRef) ([]byte, error) {
+	var index uint64
+	if ref.collided {
+		index = s.collisions[ref.key]
+	} else {
+		index = s.hashmap[ref.hashedKey]
+	}
+	if index == 0 {
+		return nil, ErrEntryNotFound
+	}
+	return s.entries.Get(int(index))
+}
@@ -0,0 +1,198 @@
+package largecache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCollidingKeysCoexist(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := New(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         100 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntriesSize:     256,
+		Hasher:             hashSub(5),
+	})
+	defer cache.Close()
+
+	// when
+	noError(t, cache.Set("liquid", []byte("value")))
+	noError(t, cache.Set("costarring", []byte("value 2")))
+	noError(t, cache.Set("liquid", []byte("value 3")))
+	noError(t, cache.Set("costarring", []byte("value 4")))
+
+	// then
+	cachedValue, err := cache.Get("liquid")
+	noError(t, err)
+	assertEqual(t, []byte("value 3"), cachedValue)
+	cachedValue, err = cache.Get("costarring")
+	noError(t, err)
+	assertEqual(t, []byte("value 4"), cachedValue)
+	_, err = cache.Get("other")
+	assertEqual(t, ErrEntryNotFound, err)
+	assertEqual(t, 2, cache.Len())
+	assertEqual(t, int64(1), cache.Stats().Collision)
+}
+
+func TestDeleteCollidingKey(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := New(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         100 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntriesSize:     256,
+		Hasher:             hashSub(5),
+	})
+	defer cache.Close()
+	noError(t, cache.Set("a", []byte("1")))
+	noError(t, cache.Set("b", []byte("2")))
+	noError(t, cache.Set("c", []byte("3")))
+
+	// when
+	noError(t, cache.Delete("a"))
+	noError(t, cache.Delete("c"))
+
+	// then
+	_, err := cache.Get("a")
+	assertEqual(t, ErrEntryNotFound, err)
+	_, err = cache.Get("c")
+	assertEqual(t, ErrEntryNotFound, err)
+	cachedValue, err := cache.Get("b")
+	noError(t, err)
+	assertEqual(t, []byte("2"), cachedValue)
+	assertEqual(t, 1, cache.Len())
+	assertEqual(t, ErrEntryNotFound, cache.Delete("c"))
+
+	// when
+	noError(t, cache.Set("d", []byte("4")))
+
+	// then
+	cachedValue, err = cache.Get("d")
+	noError(t, err)
+	assertEqual(t, []byte("4"), cachedValue)
+	assertEqual(t, 2, cache.Len())
+}
+
+func TestCollidingKeysAreEvictedAndResized(t *testing.T) {
+	t.Parallel()
+
+	// given
+	var removed []string
+	cache, _ := New(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         100 * time.Second,
+		MaxEntriesInWindow: 100,
+		MaxEntriesSize:     256,
+		HardMaxCacheSize:   1,
+		Hasher:             hashSub(5),
+		OnRemove: func(key string, entry []byte) {
+			removed = append(removed, key)
+		},
+	})
+	defer cache.Close()
+	value := blob('a', 1024*300)
+
+	// when
+	for _, key := range []string{"a", "b", "c", "d"} {
+		noError(t, cache.Set(key, value))
+	}
+
+	// then
+	assertEqual(t, []string{"a"}, removed)
+	assertEqual(t, 3, cache.Len())
+
+	// when
+	noError(t, cache.SetMaxSize(2))
+
+	// then
+	for _, key := range []string{"b", "c", "d"} {
+		cachedValue, err := cache.Get(key)
+		noError(t, err)
+		assertEqual(t, value, cachedValue)
+	}
+	_, err := cache.Get("a")
+	assertEqual(t, ErrEntryNotFound, err)
+	assertEqual(t, int64(3), cache.Stats().Collision)
+}
+
+func TestCollidingKeysAreWalked(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := New(context.Background(), Config{
+		Shards:             2,
+		LifeWindow:         100 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntriesSize:     256,
+		Hasher:             hashSub(5),
+	})
+	defer cache.Close()
+	noError(t, cache.Set("a", []byte("1")))
+	noError(t, cache.Set("b", []byte("2")))
+	noError(t, cache.Set("c", []byte("3")))
+
+	// when
+	iterated := 0
+	for it := cache.Iterator(); it.SetNext(); iterated++ {
+		_, err := it.Value()
+		noError(t, err)
+	}
+	prefixed := 0
+	for it := cache.ScanPrefix("b"); it.SetNext(); prefixed++ {
+	}
+	scanned := 0
+	for cursor := uint64(0); ; {
+		var page []EntryInfo
+		page, cursor = cache.Scan(cursor, 1)
+		scanned += len(page)
+		if cursor == 0 {
+			break
+		}
+	}
+	visited, err := cache.ForEach(context.Background(), 2, func(EntryInfo) error { return nil })
+
+	// then
+	noError(t, err)
+	assertEqual(t, 3, cache.Len())
+	assertEqual(t, 3, iterated)
+	assertEqual(t, 1, prefixed)
+	assertEqual(t, 3, scanned)
+	assertEqual(t, 3, visited)
+}
+
+func TestInvalidateTagOfCollidingKeys(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := New(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         100 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntriesSize:     256,
+		Hasher:             hashSub(5),
+	})
+	defer cache.Close()
+	noError(t, cache.SetWithTags("a", []byte("1"), "first"))
+	noError(t, cache.SetWithTags("b", []byte("2"), "second"))
+	noError(t, cache.SetWithTags("c", []byte("3"), "second"))
+
+	// when
+	removed, err := cache.InvalidateTag("second")
+
+	// then
+	noError(t, err)
+	assertEqual(t, 2, removed)
+	_, err = cache.Get("b")
+	assertEqual(t, ErrEntryNotFound, err)
+	_, err = cache.Get("c")
+	assertEqual(t, ErrEntryNotFound, err)
+	cachedValue, err := cache.Get("a")
+	noError(t, err)
+	assertEqual(t, []byte("1"), cachedValue)
+}
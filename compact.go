@@ -0,0 +1,56 @@
+package largecache
+
+// Compact moves the entries of every shard to an array sized for them, releasing the memory
+// left over after a spike. Shards are compacted one at a time, regardless of CompactionThreshold.
+// It returns the number of bytes released.
+func (c *LargeCache) Compact() int {
+	released := 0
+	for _, shard := range c.shards {
+		released += shard.compact(1)
+	}
+	return released
+}
+
+// compactNext compacts the first shard from compactCursor whose entries fill less than
+// CompactionThreshold of its array. The CleanWindow ticker calls it, so a tick rebuilds one shard at most.
+func (c *LargeCache) compactNext() {
+	for i := 0; i < len(c.shards); i++ {
+		shard := c.shards[c.compactCursor]
+		c.compactCursor = (c.compactCursor + 1) % len(c.shards)
+		if shard.compact(c.config.CompactionThreshold) > 0 {
+			return
+		}
+	}
+}
+
+// compact rebuilds the queue into a smaller array when its live entries take less than threshold
+// of the current one, and returns the number of bytes released.
+func (s *cacheShard) compact(threshold float64) int {
+	s.lock.RLock()
+	size, capacity := s.entries.Size()-s.deadBytes, s.entries.Capacity()
+	s.lock.RUnlock()
+	if float64(size) >= threshold*float64(capacity) || s.compactedCapacity(size) >= capacity {
+		return 0
+	}
+
+	s.lock.Lock()
+	size, capacity = s.entries.Size()-s.deadBytes, s.entries.Capacity()
+	target := s.compactedCapacity(size)
+	if target >= capacity {
+		s.lock.Unlock()
+		return 0
+	}
+	s.rebuildWithoutLock(target, s.entries.MaxCapacity())
+	s.lock.Unlock()
+	return capacity - target
+}
+
+// compactedCapacity leaves room for as many bytes as the entries take before the queue grows again,
+// without going below the initial capacity of the shard.
+func (s *cacheShard) compactedCapacity(size int) int {
+	target := max(2*size, s.initialCapacity)
+	if maxCapacity := s.entries.MaxCapacity(); maxCapacity > 0 && target > maxCapacity {
+		target = maxCapacity
+	}
+	return target
+}
@@ -0,0 +1,102 @@
+package largecache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func newCompactionTestCache(shards int, threshold float64, clock clock) *LargeCache {
+	cache, _ := newLargeCache(context.Background(), Config{
+		Shards:              shards,
+		LifeWindow:          time.Second,
+		MaxEntriesInWindow:  100 * shards,
+		MaxEntriesSize:      256,
+		CompactionThreshold: threshold,
+	}, clock)
+	return cache
+}
+
+func TestCompactShrinksQueueAfterSpike(t *testing.T) {
+	t.Parallel()
+
+	// given
+	clock := mockedClock{value: 0}
+	cache := newCompactionTestCache(1, 0, &clock)
+	defer cache.Close()
+	value := blob('a', 10*1024)
+	for i := 0; i < 100; i++ {
+		noError(t, cache.Set(fmt.Sprintf("key%d", i), value))
+	}
+	clock.set(5)
+	noError(t, cache.Set("fresh", value))
+	cache.cleanUp(5)
+	capacity := cache.Capacity()
+
+	// when
+	released := cache.Compact()
+
+	// then
+	assertEqual(t, 100*256, cache.Capacity())
+	assertEqual(t, capacity-100*256, released)
+	cachedValue, err := cache.Get("fresh")
+	noError(t, err)
+	assertEqual(t, value, cachedValue)
+	assertEqual(t, 1, cache.Len())
+
+	// when
+	noError(t, cache.Set("next", value))
+
+	// then
+	cachedValue, err = cache.Get("next")
+	noError(t, err)
+	assertEqual(t, value, cachedValue)
+	assertEqual(t, 0, cache.Compact())
+}
+
+func TestCleanUpCompactsOneShardPerTick(t *testing.T) {
+	t.Parallel()
+
+	// given
+	clock := mockedClock{value: 0}
+	cache := newCompactionTestCache(2, 0.5, &clock)
+	defer cache.Close()
+	value := blob('a', 10*1024)
+	for i := 0; i < 200; i++ {
+		noError(t, cache.Set(fmt.Sprintf("key%d", i), value))
+	}
+	clock.set(5)
+
+	// when
+	cache.cleanUp(5)
+
+	// then
+	compacted := 0
+	for _, shard := range cache.shards {
+		if shard.capacity() == 100*256 {
+			compacted++
+		}
+	}
+	assertEqual(t, 1, compacted)
+
+	// when
+	cache.cleanUp(5)
+
+	// then
+	assertEqual(t, 2*100*256, cache.Capacity())
+}
+
+func TestInvalidCompactionThreshold(t *testing.T) {
+	t.Parallel()
+
+	// given
+	config := DefaultConf(5 * time.Second)
+	config.CompactionThreshold = 1.5
+
+	// when
+	_, err := New(context.Background(), config)
+
+	// then
+	assertEqual(t, "CompactionThreshold must be between 0 and 1", err.Error())
+}
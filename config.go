@@ -22,6 +22,47 @@ type Config struct {
 	OnRemove             func(key string, entry []byte)
 	OnRemoveWithMetadata func(key string, entry []byte, keyMetadata Metadata)
 	OnRemoveWithReason   func(key string, entry []byte, reason RemoveReason)
+	// Max number of entries in the cache, divided evenly among shards. 0 means no limit.
+	// It must be at least Shards, each shard holds MaxEntries/Shards entries rounded down.
+	// A shard holding its share evicts entries for new keys as if it had run out of space.
+	MaxEntries int
+	// Count LifeWindow from the last read of an entry instead of its write (time-to-idle).
+	// A read moves the entry to the end of its shard queue, at most once per second.
+	IdleExpiration bool
+	// Hard limit on the age of an entry since its write when IdleExpiration is set, 0 means no limit.
+	MaxAge time.Duration
+	// Time for which GetOrLoad records a key as absent when its loader returns ErrKnownAbsent.
+	// If set to < 1 second the key is not recorded.
+	NegativeLifeWindow time.Duration
+	// Policy choosing entries evicted when a shard runs out of space, FIFO by default.
+	// LRU and LFU record every read under the shard write lock.
+	EvictionPolicy EvictionPolicy
+	// Policy deciding whether a new key may evict an entry from a full shard, AdmitAll by default.
+	// New keys rejected by TinyLFU are dropped without an error.
+	AdmissionPolicy AdmissionPolicy
+	// Backing store written by every write of the cache before the shard applies it, and read by GetThrough.
+	// Shards are not locked during Store calls. Entries loaded by GetOrLoad and GetStale, expirations
+	// and evictions are not propagated to it, Namespace writes are rejected.
+	Store     Store
+	StoreMode StoreMode
+	// Time limit of each WriteThrough call of the Store, on top of the context passed to New. 0 means no limit.
+	StoreTimeout time.Duration
+	// Interval between flushes of WriteBehind mutations. Defaults to 1 second when 0.
+	WriteBehindInterval time.Duration
+	// Number of pending WriteBehind mutations which triggers a flush before the interval passes, 0 disables it.
+	WriteBehindBatchSize int
+	// Number of times a failed WriteBehind mutation is retried on later flushes before it is dropped.
+	WriteBehindRetries int
+	// Called with WriteBehind mutations which could not be saved. Failures are logged when nil.
+	OnStoreError func(key string, err error)
+	// Fraction of a shard's array under which the bytes held by its entries make the CleanWindow ticker
+	// move them to a smaller array, one shard per tick. 0 disables it, Compact can still be called.
+	CompactionThreshold float64
+	// Share of a shard's queued bytes held by deleted and overwritten entries above which the CleanWindow
+	// ticker copies its live entries forward, one shard per tick. 0 disables it.
+	DefragmentationThreshold float64
+	// Max number of background refreshes started by GetStale running at once. Defaults to 16 when 0.
+	MaxConcurrentRefreshes int
 
 	onRemoveFilter int
 
@@ -57,6 +98,20 @@ func (c Config) maximumShardSizeInBytes() int {
 	return maxShardSize
 }
 
+func (c Config) maximumEntriesInShard() int {
+	if c.MaxEntries == 0 {
+		return 0
+	}
+	return c.MaxEntries / c.Shards
+}
+
+func (c Config) maxAgeInSeconds() uint64 {
+	if !c.IdleExpiration {
+		return 0
+	}
+	return uint64(c.MaxAge.Seconds())
+}
+
 func (c Config) OnRemoveFilterSet(reasons ...RemoveReason) Config {
 	c.onRemoveFilter = 0
 	for i := range reasons {
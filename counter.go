@@ -0,0 +1,122 @@
+package largecache
+
+import (
+	"math"
+	"strconv"
+)
+
+// Incr increments the counter stored under the key by one.
+func (c *LargeCache) Incr(key string) (int64, error) {
+	return c.IncrBy(key, 1)
+}
+
+// Decr decrements the counter stored under the key by one.
+func (c *LargeCache) Decr(key string) (int64, error) {
+	return c.IncrBy(key, -1)
+}
+
+// IncrBy adds delta to the counter stored under the key and returns the new value.
+// A missing or expired key is created as if it held 0.
+func (c *LargeCache) IncrBy(key string, delta int64) (int64, error) {
+	return c.IncrByWithInitial(key, delta, delta)
+}
+
+// IncrByWithInitial adds delta to the counter stored under the key and returns the new value.
+// A missing or expired key is created with the initial value instead.
+// Counters are stored as decimal text, so they can be read with Get and written with Set.
+// Each increment rewrites the entry like Set, renewing its LifeWindow and keeping its explicit expiry.
+func (c *LargeCache) IncrByWithInitial(key string, delta int64, initial int64) (int64, error) {
+	hashedKey := c.hash.Sum64(key)
+	shard := c.getShard(hashedKey)
+	if c.store == nil {
+		return shard.incrBy(key, hashedKey, delta, initial)
+	}
+
+	unlock := c.store.lockKey(key)
+	defer unlock()
+	next, err := shard.nextCounter(key, hashedKey, delta, initial)
+	if err != nil {
+		return 0, err
+	}
+	if err := c.store.save(key, next.encoded); err != nil {
+		return 0, err
+	}
+	err = shard.writeIfVersion(key, hashedKey, next.version, func(currentTimestamp uint64) error {
+		return shard.setWithoutLock(currentTimestamp, defaultNamespace, key, hashedKey, next.encoded, next.expiry)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return next.value, nil
+}
+
+func (s *cacheShard) incrBy(key string, hashedKey uint64, delta int64, initial int64) (int64, error) {
+	currentTimestamp := uint64(s.clock.Epoch())
+	var buffer [20]byte
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	wrappedEntry, ok := s.getLiveEntryWithoutLock(defaultNamespace, key, hashedKey, currentTimestamp)
+	if !ok {
+		encoded := strconv.AppendInt(buffer[:0], initial, 10)
+		if err := s.setWithoutLock(currentTimestamp, defaultNamespace, key, hashedKey, encoded, 0); err != nil {
+			return 0, err
+		}
+		return initial, nil
+	}
+
+	entry := readEntryNoCopy(wrappedEntry)
+	value, err := addToCounter(entry, delta)
+	if err != nil {
+		return 0, err
+	}
+	encoded := strconv.AppendInt(buffer[:0], value, 10)
+	if err := s.setWithoutLock(currentTimestamp, defaultNamespace, key, hashedKey, encoded, readExpiryFromEntry(wrappedEntry)); err != nil {
+		return 0, err
+	}
+	return value, nil
+}
+
+// counterUpdate is the next value of a counter read under the read lock, written once Config.Store saved it
+type counterUpdate struct {
+	value   int64
+	encoded []byte
+	expiry  uint64
+	version uint64
+}
+
+func (s *cacheShard) nextCounter(key string, hashedKey uint64, delta int64, initial int64) (counterUpdate, error) {
+	currentTimestamp := uint64(s.clock.Epoch())
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	next := counterUpdate{value: initial}
+	if index, _ := s.findWithoutLock(defaultNamespace, key, hashedKey); index != 0 {
+		if wrappedEntry, err := s.entries.Get(int(index)); err == nil {
+			next.version = readVersionFromEntry(wrappedEntry)
+			if !isAbsentEntry(wrappedEntry) && !s.isExpired(wrappedEntry, currentTimestamp) {
+				value, err := addToCounter(readEntryNoCopy(wrappedEntry), delta)
+				if err != nil {
+					return next, err
+				}
+				next.value = value
+				next.expiry = readExpiryFromEntry(wrappedEntry)
+			}
+		}
+	}
+	next.encoded = strconv.AppendInt(nil, next.value, 10)
+	return next, nil
+}
+
+// addToCounter parses the value of a counter and adds delta to it.
+func addToCounter(entry []byte, delta int64) (int64, error) {
+	value, err := strconv.ParseInt(bytesToString(entry), 10, 64)
+	if err != nil {
+		return 0, ErrNotNumeric
+	}
+	if (delta > 0 && value > math.MaxInt64-delta) || (delta < 0 && value < math.MinInt64-delta) {
+		return 0, ErrCounterOverflow
+	}
+	return value + delta, nil
+}
@@ -0,0 +1,141 @@
+package largecache
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestIncrBy(t *testing.T) {
+	t.Parallel()
+
+	cache, _ := New(context.Background(), Config{
+		Shards:             8,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1000,
+		MaxEntriesSize:     256,
+	})
+	defer cache.Close()
+
+	value, err := cache.IncrBy("counter", 5)
+	noError(t, err)
+	assertEqual(t, int64(5), value)
+
+	value, err = cache.IncrBy("counter", 5)
+	noError(t, err)
+	assertEqual(t, int64(10), value)
+
+	value, err = cache.Decr("counter")
+	noError(t, err)
+	assertEqual(t, int64(9), value)
+
+	entry, _ := cache.Get("counter")
+	assertEqual(t, []byte("9"), entry)
+
+	value, err = cache.IncrByWithInitial("other", 1, 100)
+	noError(t, err)
+	assertEqual(t, int64(100), value)
+
+	value, err = cache.Incr("other")
+	noError(t, err)
+	assertEqual(t, int64(101), value)
+}
+
+func TestIncrByNotNumeric(t *testing.T) {
+	t.Parallel()
+
+	cache, _ := New(context.Background(), Config{
+		Shards:             8,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1000,
+		MaxEntriesSize:     256,
+	})
+	defer cache.Close()
+	cache.Set("key", []byte("value"))
+
+	_, err := cache.Incr("key")
+	assertEqual(t, ErrNotNumeric, err)
+
+	cache.Set("key", []byte(strconv.FormatInt(math.MaxInt64, 10)))
+	_, err = cache.Incr("key")
+	assertEqual(t, ErrCounterOverflow, err)
+}
+
+func TestIncrByParallel(t *testing.T) {
+	t.Parallel()
+
+	cache, _ := New(context.Background(), Config{
+		Shards:             8,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1000,
+		MaxEntriesSize:     256,
+	})
+	defer cache.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				cache.Incr("counter")
+			}
+		}()
+	}
+	wg.Wait()
+
+	entry, err := cache.Get("counter")
+	noError(t, err)
+	assertEqual(t, []byte("1000"), entry)
+}
+
+func TestIncrByWithInitialError(t *testing.T) {
+	t.Parallel()
+
+	cache, _ := New(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntriesSize:     256,
+		Store:              &failingStore{MemoryStore: NewMemoryStore(), failures: 1},
+	})
+	defer cache.Close()
+
+	value, err := cache.IncrByWithInitial("counter", 1, 100)
+	assertEqual(t, "store unavailable", err.Error())
+	assertEqual(t, int64(0), value)
+	_, err = cache.Get("counter")
+	assertEqual(t, ErrEntryNotFound, err)
+}
+
+func TestIncrByRenewsLifeWindow(t *testing.T) {
+	t.Parallel()
+
+	// given
+	clock := mockedClock{value: 0}
+	cache, _ := newLargeCache(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1000,
+		MaxEntriesSize:     256,
+	}, &clock)
+	defer cache.Close()
+	clock.set(1)
+	cache.Incr("counter")
+
+	// when
+	clock.set(5)
+	value, err := cache.Incr("counter")
+	clock.set(8)
+	cache.cleanUp(uint64(clock.Epoch()))
+
+	// then
+	noError(t, err)
+	assertEqual(t, int64(2), value)
+	entry, err := cache.Get("counter")
+	noError(t, err)
+	assertEqual(t, []byte("2"), entry)
+}
@@ -0,0 +1,68 @@
+package largecache
+
+// Fragmentation returns the share of the bytes held by shard queues which belong to deleted
+// and overwritten entries, waiting to drift to the head of their queue.
+func (c *LargeCache) Fragmentation() float64 {
+	var dead, size int
+	for _, shard := range c.shards {
+		shard.lock.RLock()
+		dead += shard.deadBytes
+		size += shard.entries.Size()
+		shard.lock.RUnlock()
+	}
+	if size == 0 {
+		return 0
+	}
+	return float64(dead) / float64(size)
+}
+
+// defragmentNext defragments the first shard from defragCursor whose fragmentation exceeds
+// DefragmentationThreshold. The CleanWindow ticker calls it, so a tick rebuilds one shard at most.
+func (c *LargeCache) defragmentNext() {
+	for i := 0; i < len(c.shards); i++ {
+		shard := c.shards[c.defragCursor]
+		c.defragCursor = (c.defragCursor + 1) % len(c.shards)
+		if shard.defragment(c.config.DefragmentationThreshold) {
+			return
+		}
+	}
+}
+
+// defragment copies the live entries forward into an array of the same capacity
+// when dead bytes take more than threshold of the queue.
+func (s *cacheShard) defragment(threshold float64) bool {
+	s.lock.RLock()
+	fragmented := s.isFragmentedWithoutLock(threshold)
+	s.lock.RUnlock()
+	if !fragmented {
+		return false
+	}
+
+	s.lock.Lock()
+	fragmented = s.isFragmentedWithoutLock(threshold)
+	if fragmented {
+		s.rebuildWithoutLock(s.entries.Capacity(), s.entries.MaxCapacity())
+	}
+	s.lock.Unlock()
+	return fragmented
+}
+
+func (s *cacheShard) isFragmentedWithoutLock(threshold float64) bool {
+	return s.deadBytes > 0 && float64(s.deadBytes) > threshold*float64(s.entries.Size())
+}
+
+// markDeadWithoutLock resets the hash of an entry left in the queue after its key was deleted
+// or written again, and counts its bytes as dead until it is popped.
+func (s *cacheShard) markDeadWithoutLock(wrappedEntry []byte) {
+	resetHashFromEntry(wrappedEntry)
+	markEntryDead(wrappedEntry)
+	s.deadBytes += len(wrappedEntry)
+}
+
+// releaseDeadWithoutLock uncounts a dead entry popped from the queue. The filler pushed by the queue
+// when it grows around its head has a zero hash too, it was never counted and is skipped.
+func (s *cacheShard) releaseDeadWithoutLock(wrappedEntry []byte) {
+	if isDeadEntry(wrappedEntry) {
+		s.deadBytes -= len(wrappedEntry)
+	}
+}
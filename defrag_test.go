@@ -0,0 +1,119 @@
+package largecache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func newDefragmentationTestCache(threshold float64, clock clock) *LargeCache {
+	cache, _ := newLargeCache(context.Background(), Config{
+		Shards:                   1,
+		LifeWindow:               time.Second,
+		MaxEntriesInWindow:       100,
+		MaxEntriesSize:           256,
+		DefragmentationThreshold: threshold,
+	}, clock)
+	return cache
+}
+
+func TestFragmentationCountsDeadEntries(t *testing.T) {
+	t.Parallel()
+
+	// given
+	clock := mockedClock{value: 0}
+	cache := newDefragmentationTestCache(0, &clock)
+	defer cache.Close()
+	value := blob('a', 100)
+
+	// when dead entries sit behind a live one, so they are not popped from the head
+	noError(t, cache.Set("kex", value))
+	noError(t, cache.Set("key", value))
+	noError(t, cache.Set("key", value))
+	noError(t, cache.Set("kez", value))
+	noError(t, cache.Delete("kez"))
+
+	// then two of four entries of the same size are dead, queue headers aside
+	fragmentation := cache.Fragmentation()
+	assertEqual(t, true, fragmentation > 0.49 && fragmentation < 0.5)
+
+	// when
+	clock.set(5)
+	noError(t, cache.Set("fresh", value))
+	cache.cleanUp(5)
+
+	// then
+	assertEqual(t, 0.0, cache.Fragmentation())
+	assertEqual(t, 0, cache.shards[0].deadBytes)
+}
+
+func TestCleanUpDefragmentsShard(t *testing.T) {
+	t.Parallel()
+
+	// given
+	clock := mockedClock{value: 0}
+	cache := newDefragmentationTestCache(0.5, &clock)
+	defer cache.Close()
+	value := blob('a', 1024)
+	for i := 0; i < 10; i++ {
+		noError(t, cache.Set("key", value))
+	}
+	noError(t, cache.Set("other", value))
+	capacity := cache.Capacity()
+
+	// when
+	cache.cleanUp(0)
+
+	// then
+	assertEqual(t, 0.0, cache.Fragmentation())
+	assertEqual(t, capacity, cache.Capacity())
+	assertEqual(t, 2, cache.Len())
+	for _, key := range []string{"key", "other"} {
+		cachedValue, err := cache.Get(key)
+		noError(t, err)
+		assertEqual(t, value, cachedValue)
+	}
+	noError(t, cache.Set("key", value))
+	cachedValue, err := cache.Get("key")
+	noError(t, err)
+	assertEqual(t, value, cachedValue)
+}
+
+func TestPoppedFillerIsNotUncountedFromDeadBytes(t *testing.T) {
+	t.Parallel()
+
+	// given
+	clock := mockedClock{value: 0}
+	cache := newDefragmentationTestCache(0, &clock)
+	defer cache.Close()
+	shard := cache.shards[0]
+	dead := wrapEntry(0, 0, 1, defaultNamespace, 1, "key", blob('a', 100), &shard.entryBuffer)
+	shard.markDeadWithoutLock(dead)
+	filler := make([]byte, headersSizeInBytes)
+
+	// when
+	shard.releaseDeadWithoutLock(filler)
+
+	// then
+	assertEqual(t, len(dead), shard.deadBytes)
+
+	// when
+	shard.releaseDeadWithoutLock(dead)
+
+	// then
+	assertEqual(t, 0, shard.deadBytes)
+}
+
+func TestInvalidDefragmentationThreshold(t *testing.T) {
+	t.Parallel()
+
+	// given
+	config := DefaultConf(5 * time.Second)
+	config.DefragmentationThreshold = -1
+
+	// when
+	_, err := New(context.Background(), config)
+
+	// then
+	assertEqual(t, "DefragmentationThreshold must be between 0 and 1", err.Error())
+}
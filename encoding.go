@@ -1,15 +1,38 @@
 package largecache
 
-import "encoding/binary"
+import (
+	"encoding/binary"
+	"strings"
+)
 
 const (
 	timestampSizeInBytes = 8
 	hashSizeInBytes      = 8
+	expirySizeInBytes    = 8
+	versionSizeInBytes   = 8
+	flagsSizeInBytes     = 1
+	namespaceSizeInBytes = 4
 	keySizeInBytes       = 2
-	headersSizeInBytes    = timestampSizeInBytes + hashSizeInBytes + keySizeInBytes
+	headersSizeInBytes   = timestampSizeInBytes + hashSizeInBytes + expirySizeInBytes + versionSizeInBytes + flagsSizeInBytes + namespaceSizeInBytes + keySizeInBytes
+
+	expiryOffset    = timestampSizeInBytes + hashSizeInBytes
+	versionOffset   = expiryOffset + expirySizeInBytes
+	flagsOffset     = versionOffset + versionSizeInBytes
+	namespaceOffset = flagsOffset + flagsSizeInBytes
+	keyLengthOffset = namespaceOffset + namespaceSizeInBytes
+)
+
+const (
+	// flagAbsent marks an entry recording that the key is known to be absent, it has no value
+	flagAbsent byte = 1 << iota
+	// flagTagged marks an entry listed in the tag index of its shard
+	flagTagged
+	// flagDead marks an entry left in the queue after its key was deleted or written again,
+	// the fillers pushed by the queue are all zero and so never carry it
+	flagDead
 )
 
-func wrapEntry(timestamp uint64, hash uint64, key string, entry []byte, buffer *[]byte) []byte {
+func wrapEntry(timestamp uint64, expiry uint64, version uint64, namespace uint32, hash uint64, key string, entry []byte, buffer *[]byte) []byte {
 	keyLength := len(key)
 	blobLength := len(entry) + headersSizeInBytes + keyLength
 
@@ -20,7 +43,11 @@ func wrapEntry(timestamp uint64, hash uint64, key string, entry []byte, buffer *
 
 	binary.LittleEndian.PutUint64(blob, timestamp)
 	binary.LittleEndian.PutUint64(blob[timestampSizeInBytes:], hash)
-	binary.LittleEndian.PutUint16(blob[timestampSizeInBytes+hashSizeInBytes:], uint16(keyLength))
+	binary.LittleEndian.PutUint64(blob[expiryOffset:], expiry)
+	binary.LittleEndian.PutUint64(blob[versionOffset:], version)
+	blob[flagsOffset] = 0
+	binary.LittleEndian.PutUint32(blob[namespaceOffset:], namespace)
+	binary.LittleEndian.PutUint16(blob[keyLengthOffset:], uint16(keyLength))
 	copy(blob[headersSizeInBytes:], key)
 	copy(blob[headersSizeInBytes+keyLength:], entry)
 
@@ -43,7 +70,7 @@ func appendToWrappedEntry(timestamp uint64, wrappedEntry []byte, entry []byte, b
 }
 
 func readEntry(data []byte) []byte {
-	length := binary.LittleEndian.Uint16(data[timestampSizeInBytes+hashSizeInBytes:])
+	length := binary.LittleEndian.Uint16(data[keyLengthOffset:])
 
 	dst := make([]byte, len(data)-int(headersSizeInBytes+length))
 	copy(dst, data[headersSizeInBytes+length:])
@@ -51,12 +78,70 @@ func readEntry(data []byte) []byte {
 	return dst
 }
 
+// readEntryNoCopy returns the value of the wrapped entry without copying it,
+// the slice is only valid while the shard lock is held.
+func readEntryNoCopy(data []byte) []byte {
+	length := binary.LittleEndian.Uint16(data[keyLengthOffset:])
+
+	return data[headersSizeInBytes+int(length):]
+}
+
 func readTimestampFromEntry(data []byte) uint64 {
 	return binary.LittleEndian.Uint64(data)
 }
 
+func writeTimestampToEntry(data []byte, timestamp uint64) {
+	binary.LittleEndian.PutUint64(data, timestamp)
+}
+
+// readExpiryFromEntry returns the absolute expiry of the entry in seconds,
+// or 0 when the entry follows the shard's LifeWindow.
+func readExpiryFromEntry(data []byte) uint64 {
+	return binary.LittleEndian.Uint64(data[expiryOffset:])
+}
+
+func writeExpiryToEntry(data []byte, expiry uint64) {
+	binary.LittleEndian.PutUint64(data[expiryOffset:], expiry)
+}
+
+func readVersionFromEntry(data []byte) uint64 {
+	return binary.LittleEndian.Uint64(data[versionOffset:])
+}
+
+func writeVersionToEntry(data []byte, version uint64) {
+	binary.LittleEndian.PutUint64(data[versionOffset:], version)
+}
+
+func isAbsentEntry(data []byte) bool {
+	return data[flagsOffset]&flagAbsent != 0
+}
+
+func markEntryAbsent(data []byte) {
+	data[flagsOffset] |= flagAbsent
+}
+
+func isTaggedEntry(data []byte) bool {
+	return data[flagsOffset]&flagTagged != 0
+}
+
+func markEntryTagged(data []byte) {
+	data[flagsOffset] |= flagTagged
+}
+
+func isDeadEntry(data []byte) bool {
+	return data[flagsOffset]&flagDead != 0
+}
+
+func markEntryDead(data []byte) {
+	data[flagsOffset] |= flagDead
+}
+
+func readNamespaceFromEntry(data []byte) uint32 {
+	return binary.LittleEndian.Uint32(data[namespaceOffset:])
+}
+
 func readKeyFromEntry(data []byte) string {
-	length := binary.LittleEndian.Uint16(data[timestampSizeInBytes+hashSizeInBytes:])
+	length := binary.LittleEndian.Uint16(data[keyLengthOffset:])
 
 	dst := make([]byte, length)
 	copy(dst, data[headersSizeInBytes:headersSizeInBytes+length])
@@ -65,15 +150,21 @@ func readKeyFromEntry(data []byte) string {
 }
 
 func compareKeyFromEntry(data []byte, key string) bool {
-	length := binary.LittleEndian.Uint16(data[timestampSizeInBytes+hashSizeInBytes:])
+	length := binary.LittleEndian.Uint16(data[keyLengthOffset:])
 
 	return bytesToString(data[headersSizeInBytes:headersSizeInBytes+length]) == key
 }
 
+func hasKeyPrefix(data []byte, prefix string) bool {
+	length := binary.LittleEndian.Uint16(data[keyLengthOffset:])
+
+	return strings.HasPrefix(bytesToString(data[headersSizeInBytes:headersSizeInBytes+length]), prefix)
+}
+
 func readHashFromEntry(data []byte) uint64 {
 	return binary.LittleEndian.Uint64(data[timestampSizeInBytes:])
 }
 
 func resetHashFromEntry(data []byte) {
-	binary.LittleEndian.PutUint16(data[timestampSizeInBytes:], 0)
+	binary.LittleEndian.PutUint64(data[timestampSizeInBytes:], 0)
 }
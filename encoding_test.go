@@ -12,7 +12,7 @@ func TestEncodeDecode(t *testing.T) {
 	data := []byte("data")
 	buffer := make([]byte, 100)
 
-	wrapped := wrapEntry(now, hash, key, data, &buffer)
+	wrapped := wrapEntry(now, 0, 0, 0, hash, key, data, &buffer)
 
 	assertEqual(t, key, readKeyFromEntry(wrapped))
 	assertEqual(t, hash, readHashFromEntry(wrapped))
@@ -28,7 +28,7 @@ func TestAllocateBiggerBuffer(t *testing.T) {
 	data := []byte("2")
 	buffer := make([]byte, 1)
 
-	wrapped := wrapEntry(now, hash, key, data, &buffer)
+	wrapped := wrapEntry(now, 0, 0, 0, hash, key, data, &buffer)
 
 	assertEqual(t, key, readKeyFromEntry(wrapped))
 	assertEqual(t, hash, readHashFromEntry(wrapped))
@@ -36,3 +36,27 @@ func TestAllocateBiggerBuffer(t *testing.T) {
 	assertEqual(t, data, readEntry(wrapped))
 	assertEqual(t, 2+headersSizeInBytes, len(buffer))
 }
+
+func TestEncodeDecodeExpiry(t *testing.T) {
+	buffer := make([]byte, 100)
+
+	wrapped := wrapEntry(1, 42, 3, 0, 7, "key", []byte("data"), &buffer)
+	assertEqual(t, uint64(42), readExpiryFromEntry(wrapped))
+
+	writeExpiryToEntry(wrapped, 43)
+	assertEqual(t, uint64(43), readExpiryFromEntry(wrapped))
+	assertEqual(t, uint64(7), readHashFromEntry(wrapped))
+	assertEqual(t, []byte("data"), readEntry(wrapped))
+}
+
+func TestEncodeDecodeVersion(t *testing.T) {
+	buffer := make([]byte, 100)
+
+	wrapped := wrapEntry(1, 0, 3, 0, 7, "key", []byte("data"), &buffer)
+	assertEqual(t, uint64(3), readVersionFromEntry(wrapped))
+
+	writeVersionToEntry(wrapped, 4)
+	assertEqual(t, uint64(4), readVersionFromEntry(wrapped))
+	assertEqual(t, "key", readKeyFromEntry(wrapped))
+	assertEqual(t, []byte("data"), readEntry(wrapped))
+}
@@ -6,4 +6,16 @@ import (
 
 var (
 	ErrEntryNotFound = errors.New("Entry not found")
+	// ErrKnownAbsent is returned when the key was recorded as absent with SetAbsent
+	ErrKnownAbsent = errors.New("Entry is known to be absent")
+	// ErrNotNumeric is returned by counter operations when the entry is not a decimal integer
+	ErrNotNumeric = errors.New("Entry is not a number")
+	// ErrCounterOverflow is returned by counter operations when the result does not fit int64
+	ErrCounterOverflow = errors.New("Counter overflows int64")
+	// ErrStoreClosed is returned by writes once a WriteBehind Store was stopped by Close or the context of New
+	ErrStoreClosed = errors.New("Write-behind store is closed")
+	// ErrNamespaceWithStore is returned by Namespace writes when Config.Store is set, the Store has no namespaces
+	ErrNamespaceWithStore = errors.New("Namespaces cannot be written when a Store is configured")
+
+	errLoaderPanicked = errors.New("Loader panicked")
 )
@@ -0,0 +1,116 @@
+package largecache
+
+import "math"
+
+// EvictionPolicy chooses the entries evicted when a shard runs out of space.
+// Entries are stored in a FIFO queue, so LRU and LFU are approximated by giving the oldest
+// entry a second chance: instead of being evicted it is moved to the tail of the queue
+// when it was read recently (LRU) or often (LFU).
+type EvictionPolicy int
+
+const (
+	// FIFO evicts the oldest written entry
+	FIFO EvictionPolicy = iota
+	// LRU spares the oldest entry once if it was read since it was last spared
+	LRU
+	// LFU spares the oldest entry while it has reads left, halving its count each time it is spared
+	LFU
+)
+
+// maxEvictionChances bounds the number of entries moved to the tail for a single eviction
+const maxEvictionChances = 8
+
+func newAccesses(policy EvictionPolicy) map[uint64]uint32 {
+	if policy == FIFO {
+		return nil
+	}
+	return make(map[uint64]uint32)
+}
+
+func (s *cacheShard) recordEvictionAccessWithoutLock(hashedKey uint64) {
+	if _, ok := s.hashmap[hashedKey]; !ok {
+		return
+	}
+	if s.policy == LRU {
+		s.accesses[hashedKey] = 1
+	} else if count := s.accesses[hashedKey]; count < math.MaxUint32 {
+		s.accesses[hashedKey] = count + 1
+	}
+}
+
+// evictWithoutLock frees space for a new entry by removing the oldest one,
+// after moving up to maxEvictionChances entries spared by the policy to the tail.
+func (s *cacheShard) evictWithoutLock() error {
+	currentTimestamp := uint64(s.clock.Epoch())
+	for i := 0; i < maxEvictionChances && s.policy != FIFO; i++ {
+		oldest, err := s.entries.Peek()
+		if err != nil {
+			break
+		}
+		hashedKey := readHashFromEntry(oldest)
+		if hashedKey == 0 || s.isExpired(oldest, currentTimestamp) || !s.spareWithoutLock(hashedKey) {
+			break
+		}
+		s.moveOldestToTailWithoutLock(oldest, hashedKey)
+	}
+	return s.removeOldestEntry(NoSpace)
+}
+
+func (s *cacheShard) spareWithoutLock(hashedKey uint64) bool {
+	count := s.accesses[hashedKey]
+	if count == 0 {
+		return false
+	}
+	if s.policy == LRU {
+		s.accesses[hashedKey] = 0
+	} else {
+		s.accesses[hashedKey] = count / 2
+	}
+	return true
+}
+
+// moveOldestToTailWithoutLock pushes a copy of the oldest entry to the tail and pops the original.
+// The copy keeps its timestamp, so it is given an explicit expiry for the cleanUp sweep
+// to find it behind younger entries.
+func (s *cacheShard) moveOldestToTailWithoutLock(oldest []byte, hashedKey uint64) {
+	w := s.copyToEvictionBufferWithoutLock(oldest)
+	if !s.idleExpiration && readExpiryFromEntry(w) == 0 && s.lifeWindow > 0 {
+		writeExpiryToEntry(w, readTimestampFromEntry(w)+s.lifeWindow)
+	}
+
+	if s.requeueOldestWithoutLock(w, hashedKey) {
+		s.trackExpiryWithoutLock(w, hashedKey)
+	}
+}
+
+func (s *cacheShard) copyToEvictionBufferWithoutLock(oldest []byte) []byte {
+	if len(s.evictionBuffer) < len(oldest) {
+		s.evictionBuffer = make([]byte, len(oldest))
+	}
+	w := s.evictionBuffer[:len(oldest)]
+	copy(w, oldest)
+	return w
+}
+
+// requeueOldestWithoutLock pops the oldest entry and pushes its copy w to the tail.
+// It returns false when the queue has no room for the copy and the entry is evicted after all.
+func (s *cacheShard) requeueOldestWithoutLock(w []byte, hashedKey uint64) bool {
+	s.entries.Pop()
+	index, err := s.entries.Push(w)
+	if err != nil {
+		s.unindexWithoutLock(w, hashedKey)
+		if isTaggedEntry(w) {
+			s.untagWithoutLock(w)
+		}
+		if !isAbsentEntry(w) {
+			s.onRemove(w, NoSpace)
+		}
+		if s.statsEnabled {
+			delete(s.hashmapStats, hashedKey)
+		}
+		delete(s.accesses, hashedKey)
+		return false
+	}
+	s.reindexWithoutLock(w, hashedKey, uint64(index))
+	return true
+}
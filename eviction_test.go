@@ -0,0 +1,185 @@
+package largecache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func newEvictionTestCache(policy EvictionPolicy) *LargeCache {
+	cache, _ := New(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         100 * time.Second,
+		MaxEntriesInWindow: 100,
+		MaxEntriesSize:     256,
+		HardMaxCacheSize:   1,
+		EvictionPolicy:     policy,
+	})
+	return cache
+}
+
+func TestFIFOEvictsOldestEntry(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache := newEvictionTestCache(FIFO)
+	defer cache.Close()
+	value := blob('a', 1024*300)
+	noError(t, cache.Set("a", value))
+	noError(t, cache.Set("b", value))
+	noError(t, cache.Set("c", value))
+	cache.Get("a")
+
+	// when
+	noError(t, cache.Set("d", value))
+
+	// then
+	_, err := cache.Get("a")
+	assertEqual(t, ErrEntryNotFound, err)
+	_, err = cache.Get("b")
+	noError(t, err)
+}
+
+func TestLRUSparesRecentlyReadEntry(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache := newEvictionTestCache(LRU)
+	defer cache.Close()
+	value := blob('a', 1024*300)
+	noError(t, cache.Set("a", value))
+	noError(t, cache.Set("b", value))
+	noError(t, cache.Set("c", value))
+	cache.Get("a")
+
+	// when
+	noError(t, cache.Set("d", value))
+
+	// then
+	_, err := cache.Get("a")
+	noError(t, err)
+	_, err = cache.Get("b")
+	assertEqual(t, ErrEntryNotFound, err)
+	_, err = cache.Get("d")
+	noError(t, err)
+}
+
+func TestLFUSparesFrequentlyReadEntry(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache := newEvictionTestCache(LFU)
+	defer cache.Close()
+	value := blob('a', 1024*300)
+	noError(t, cache.Set("a", value))
+	noError(t, cache.Set("b", value))
+	noError(t, cache.Set("c", value))
+	for i := 0; i < 4; i++ {
+		cache.Get("a")
+	}
+	cache.Get("b")
+
+	// when
+	noError(t, cache.Set("d", value))
+	noError(t, cache.Set("e", value))
+
+	// then
+	_, err := cache.Get("a")
+	noError(t, err)
+	_, err = cache.Get("b")
+	assertEqual(t, ErrEntryNotFound, err)
+	_, err = cache.Get("c")
+	assertEqual(t, ErrEntryNotFound, err)
+}
+
+func TestInvalidEvictionPolicy(t *testing.T) {
+	t.Parallel()
+
+	// given
+	config := DefaultConf(5 * time.Second)
+	config.EvictionPolicy = LFU + 1
+
+	// when
+	_, err := New(context.Background(), config)
+
+	// then
+	assertEqual(t, "EvictionPolicy must be FIFO, LRU or LFU", err.Error())
+}
+
+func TestMaxEntriesEvictsOldestEntry(t *testing.T) {
+	t.Parallel()
+
+	// given
+	var removed []string
+	var reasons []RemoveReason
+	config := Config{
+		Shards:             8,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1000,
+		MaxEntriesSize:     256,
+	}
+	config.Shards = 1
+	config.MaxEntries = 3
+	config.OnRemoveWithReason = func(key string, entry []byte, reason RemoveReason) {
+		removed = append(removed, key)
+		reasons = append(reasons, reason)
+	}
+	cache, _ := New(context.Background(), config)
+	defer cache.Close()
+	noError(t, cache.Set("a", []byte("1")))
+	noError(t, cache.Set("b", []byte("2")))
+	noError(t, cache.Set("c", []byte("3")))
+
+	// when
+	noError(t, cache.Set("b", []byte("4")))
+	noError(t, cache.Append("c", []byte("5")))
+
+	// then
+	assertEqual(t, 3, cache.Len())
+	assertEqual(t, 0, len(removed))
+
+	// when
+	noError(t, cache.Set("d", []byte("6")))
+
+	// then
+	assertEqual(t, 3, cache.Len())
+	assertEqual(t, []string{"a"}, removed)
+	assertEqual(t, []RemoveReason{NoSpace}, reasons)
+	assertEqual(t, 3, cache.EntryCapacity())
+	assertEqual(t, int64(3), cache.Stats().MaxEntries)
+}
+
+func TestMaxEntriesIsDividedAmongShards(t *testing.T) {
+	t.Parallel()
+
+	// given
+	config := Config{
+		Shards:             8,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1000,
+		MaxEntriesSize:     256,
+	}
+	config.Shards = 4
+	config.MaxEntries = 10
+
+	// when
+	cache, _ := New(context.Background(), config)
+	defer cache.Close()
+
+	// then
+	assertEqual(t, 8, cache.EntryCapacity())
+
+	// when
+	config.MaxEntries = -1
+	_, err := New(context.Background(), config)
+
+	// then
+	assertEqual(t, "MaxEntries must be >= 0", err.Error())
+
+	// when
+	config.MaxEntries = 3
+	_, err = New(context.Background(), config)
+
+	// then
+	assertEqual(t, "MaxEntries must be >= Shards", err.Error())
+}
@@ -0,0 +1,82 @@
+package largecache
+
+import "container/heap"
+
+// expiryItem records an explicit expiry written for a key. Items are left in the heap when the entry
+// is overwritten or removed, they are skipped once the entry stored for the key has another expiry.
+type expiryItem struct {
+	expiry    uint64
+	hashedKey uint64
+	key       collisionKey
+}
+
+// expiryHeap is a min-heap of the explicit expiries of a shard
+type expiryHeap []expiryItem
+
+func (h expiryHeap) Len() int           { return len(h) }
+func (h expiryHeap) Less(i, j int) bool { return h[i].expiry < h[j].expiry }
+func (h expiryHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *expiryHeap) Push(x any) {
+	*h = append(*h, x.(expiryItem))
+}
+
+func (h *expiryHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// minimumStaleExpiries keeps small shards from dropping stale expiries on every write
+const minimumStaleExpiries = 64
+
+// trackExpiryWithoutLock records the explicit expiry of an entry written to the shard, so cleanUp
+// finds it when it sits behind younger entries in the queue.
+func (s *cacheShard) trackExpiryWithoutLock(wrappedEntry []byte, hashedKey uint64) {
+	expiry := readExpiryFromEntry(wrappedEntry)
+	if expiry == 0 {
+		return
+	}
+	if len(s.expiries) > 2*(len(s.hashmap)+len(s.collisions))+minimumStaleExpiries {
+		s.dropStaleExpiriesWithoutLock()
+	}
+	heap.Push(&s.expiries, expiryItem{expiry: expiry, hashedKey: hashedKey, key: entryCollisionKey(wrappedEntry)})
+}
+
+// dropStaleExpiriesWithoutLock forgets the expiries of overwritten and removed entries,
+// which keeps the heap proportional to the entries of the shard.
+func (s *cacheShard) dropStaleExpiriesWithoutLock() {
+	live := s.expiries[:0]
+	for _, item := range s.expiries {
+		if _, ok := s.expiringEntryWithoutLock(item); ok {
+			live = append(live, item)
+		}
+	}
+	clear(s.expiries[len(live):])
+	s.expiries = live
+	heap.Init(&s.expiries)
+}
+
+// expiringEntryWithoutLock returns the entry stored for the key of the item if it still carries its expiry.
+func (s *cacheShard) expiringEntryWithoutLock(item expiryItem) ([]byte, bool) {
+	index, _ := s.findWithoutLock(item.key.namespace, item.key.key, item.hashedKey)
+	if index == 0 {
+		return nil, false
+	}
+	wrappedEntry, err := s.entries.Get(int(index))
+	if err != nil || readExpiryFromEntry(wrappedEntry) != item.expiry {
+		return nil, false
+	}
+	return wrappedEntry, true
+}
+
+// removeExpiredWithoutLock drops the entries whose explicit expiry has passed, wherever they are in the queue.
+func (s *cacheShard) removeExpiredWithoutLock(currentTimestamp uint64) {
+	for len(s.expiries) > 0 && s.expiries[0].expiry < currentTimestamp {
+		item := heap.Pop(&s.expiries).(expiryItem)
+		if wrappedEntry, ok := s.expiringEntryWithoutLock(item); ok {
+			s.removeWithoutLock(wrappedEntry, item.hashedKey, Expried)
+		}
+	}
+}
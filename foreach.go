@@ -0,0 +1,102 @@
+package largecache
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// forEachBatchSize is the number of entries ForEach copies under one shard read lock
+const forEachBatchSize = 64
+
+// ForEach calls fn for every entry of the cache, except the entries of namespaces, processing shards concurrently with the given
+// number of workers, GOMAXPROCS when not positive. fn is called without any shard lock held
+// and must be safe for concurrent use. ForEach stops on the first error returned by fn
+// or when ctx is done and returns that error along with the number of visited entries.
+func (c *LargeCache) ForEach(ctx context.Context, workers int, fn func(EntryInfo) error) (int, error) {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > len(c.shards) {
+		workers = len(c.shards)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	shards := make(chan *cacheShard, len(c.shards))
+	for _, shard := range c.shards {
+		shards <- shard
+	}
+	close(shards)
+
+	var (
+		visited  int64
+		firstErr error
+		errOnce  sync.Once
+		wg       sync.WaitGroup
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for shard := range shards {
+				if err := shard.forEach(ctx, fn, &visited); err != nil {
+					errOnce.Do(func() {
+						firstErr = err
+						cancel()
+					})
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	return int(atomic.LoadInt64(&visited)), firstErr
+}
+
+func (s *cacheShard) forEach(ctx context.Context, fn func(EntryInfo) error, visited *int64) error {
+	refs, count := s.copyEntryRefs()
+	for start := 0; start < count; start += forEachBatchSize {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		end := start + forEachBatchSize
+		if end > count {
+			end = count
+		}
+		for _, entry := range s.readEntries(refs[start:end]) {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := fn(entry); err != nil {
+				return err
+			}
+			atomic.AddInt64(visited, 1)
+		}
+	}
+	return nil
+}
+
+// readEntries copies the referenced entries, skipping the ones removed in the meantime.
+func (s *cacheShard) readEntries(refs []entryRef) []EntryInfo {
+	entries := make([]EntryInfo, 0, len(refs))
+	s.lock.RLock()
+	for _, ref := range refs {
+		wrappedEntry, err := s.getEntryByRefWithoutLock(ref)
+		if err != nil || isAbsentEntry(wrappedEntry) || readNamespaceFromEntry(wrappedEntry) != defaultNamespace {
+			continue
+		}
+		entries = append(entries, EntryInfo{
+			timestamp: readTimestampFromEntry(wrappedEntry),
+			hash:      ref.hashedKey,
+			key:       readKeyFromEntry(wrappedEntry),
+			value:     readEntry(wrappedEntry),
+		})
+	}
+	s.lock.RUnlock()
+	return entries
+}
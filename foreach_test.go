@@ -0,0 +1,101 @@
+package largecache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestForEach(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := New(context.Background(), Config{
+		Shards:             8,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1000,
+		MaxEntriesSize:     256,
+	})
+	defer cache.Close()
+	for i := 0; i < 1000; i++ {
+		cache.Set(fmt.Sprintf("key%d", i), []byte("value"))
+	}
+
+	// when
+	var lock sync.Mutex
+	seen := make(map[string]bool)
+	visited, err := cache.ForEach(context.Background(), 4, func(entry EntryInfo) error {
+		lock.Lock()
+		seen[entry.Key()] = true
+		lock.Unlock()
+		return nil
+	})
+
+	// then
+	noError(t, err)
+	assertEqual(t, 1000, visited)
+	assertEqual(t, 1000, len(seen))
+}
+
+func TestForEachStopsOnError(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := New(context.Background(), Config{
+		Shards:             8,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1000,
+		MaxEntriesSize:     256,
+	})
+	defer cache.Close()
+	for i := 0; i < 1000; i++ {
+		cache.Set(fmt.Sprintf("key%d", i), []byte("value"))
+	}
+	stop := errors.New("stop")
+
+	// when
+	var calls int64
+	visited, err := cache.ForEach(context.Background(), 4, func(entry EntryInfo) error {
+		if atomic.AddInt64(&calls, 1) == 10 {
+			return stop
+		}
+		return nil
+	})
+
+	// then
+	assertEqual(t, stop, err)
+	if visited >= 1000 || int64(visited) >= atomic.LoadInt64(&calls) {
+		t.Errorf("expected early stop, visited %d entries in %d calls", visited, calls)
+	}
+}
+
+func TestForEachStopsOnCancel(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := New(context.Background(), Config{
+		Shards:             8,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1000,
+		MaxEntriesSize:     256,
+	})
+	defer cache.Close()
+	for i := 0; i < 1000; i++ {
+		cache.Set(fmt.Sprintf("key%d", i), []byte("value"))
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// when
+	visited, err := cache.ForEach(ctx, 1, func(entry EntryInfo) error {
+		cancel()
+		return nil
+	})
+
+	// then
+	assertEqual(t, context.Canceled, err)
+	assertEqual(t, 1, visited)
+}
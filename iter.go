@@ -0,0 +1,46 @@
+//go:build go1.23
+
+package largecache
+
+import "iter"
+
+// All returns a sequence of keys and entries of the cache, walking the shards like Iterator.
+// Entries of namespaces are not part of it.
+// Entries removed while iterating are skipped, breaking out of the loop stops the walk.
+func (c *LargeCache) All() iter.Seq2[string, []byte] {
+	return func(yield func(string, []byte) bool) {
+		for entry := range c.Entries() {
+			if !yield(entry.Key(), entry.Value()) {
+				return
+			}
+		}
+	}
+}
+
+// Keys returns a sequence of keys of the cache, see All.
+func (c *LargeCache) Keys() iter.Seq[string] {
+	return func(yield func(string) bool) {
+		for entry := range c.Entries() {
+			if !yield(entry.Key()) {
+				return
+			}
+		}
+	}
+}
+
+// Entries returns a sequence of entries of the cache, see All.
+func (c *LargeCache) Entries() iter.Seq[EntryInfo] {
+	return func(yield func(EntryInfo) bool) {
+		it := c.Iterator()
+		for it.SetNext() {
+			entry, err := it.Value()
+			if err != nil {
+				// the entry was overwritten in the queue after the shard keys were copied
+				continue
+			}
+			if !yield(entry) {
+				return
+			}
+		}
+	}
+}
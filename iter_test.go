@@ -0,0 +1,98 @@
+//go:build go1.23
+
+package largecache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestAll(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := New(context.Background(), Config{
+		Shards:             8,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1000,
+		MaxEntriesSize:     256,
+	})
+	defer cache.Close()
+	for i := 0; i < 100; i++ {
+		cache.Set(fmt.Sprintf("key%d", i), []byte(fmt.Sprintf("value%d", i)))
+	}
+
+	// when
+	entries := make(map[string]string)
+	for key, value := range cache.All() {
+		entries[key] = string(value)
+	}
+
+	// then
+	assertEqual(t, 100, len(entries))
+	assertEqual(t, "value42", entries["key42"])
+}
+
+func TestKeysStopsOnBreak(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := New(context.Background(), Config{
+		Shards:             8,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1000,
+		MaxEntriesSize:     256,
+	})
+	defer cache.Close()
+	for i := 0; i < 100; i++ {
+		cache.Set(fmt.Sprintf("key%d", i), []byte("value"))
+	}
+
+	// when
+	count := 0
+	for range cache.Keys() {
+		count++
+		if count == 10 {
+			break
+		}
+	}
+
+	// then
+	assertEqual(t, 10, count)
+}
+
+func TestEntriesSkipsRemovedEntries(t *testing.T) {
+	t.Parallel()
+
+	// given
+	clock := mockedClock{value: 0}
+	cache, _ := newLargeCache(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         10 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntriesSize:     256,
+	}, &clock)
+	defer cache.Close()
+	clock.set(3)
+	for i := 0; i < 10; i++ {
+		cache.Set(fmt.Sprintf("key%d", i), []byte("value"))
+	}
+
+	// when
+	var keys []string
+	for entry := range cache.Entries() {
+		assertEqual(t, uint64(3), entry.Timestamp())
+		keys = append(keys, entry.Key())
+		for i := 0; i < 10; i++ {
+			if key := fmt.Sprintf("key%d", i); key != entry.Key() {
+				cache.Delete(key)
+			}
+		}
+	}
+
+	// then
+	assertEqual(t, 1, len(keys))
+	assertEqual(t, 1, cache.Len())
+}
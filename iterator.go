@@ -46,47 +46,50 @@ type EntryInfoIterator struct {
 	currentShard    int
 	currentIndex    int
 	curentEntryInfo EntryInfo
-	elements        []uint64
+	elements        []entryRef
 	elementsCount   int
 	valid           bool
+
+	// scoped limits the iterator to entries of namespace with keys starting with prefix
+	scoped    bool
+	namespace uint32
+	prefix    string
 }
 
 func (it *EntryInfoIterator) SetNext() bool {
 	it.mutex.Lock()
 
+	for it.next() {
+		if empty := it.setCurrentEntry(); !empty {
+			it.mutex.Unlock()
+			return true
+		}
+	}
+	it.mutex.Unlock()
+	return false
+}
+
+// next moves to the following entry, copying the references of the next non-empty shard when needed.
+// Skipped entries are looped over rather than recursed into, a namespace iterator may skip most of them.
+func (it *EntryInfoIterator) next() bool {
 	it.valid = false
 	it.currentIndex++
 
 	if it.elementsCount > it.currentIndex {
 		it.valid = true
-
-		empty := it.setCurrentEntry()
-		it.mutex.Unlock()
-
-		if empty {
-			return it.SetNext()
-		}
 		return true
 	}
 
 	for i := it.currentShard + 1; i < it.cache.config.Shards; i++ {
-		it.elements, it.elementsCount = it.cache.shards[i].copyHashedKeys()
+		it.elements, it.elementsCount = it.cache.shards[i].copyEntryRefs()
 
 		if it.elementsCount > 0 {
 			it.currentIndex = 0
 			it.currentShard = i
 			it.valid = true
-
-			empty := it.setCurrentEntry()
-			it.mutex.Unlock()
-
-			if empty {
-				return it.SetNext()
-			}
 			return true
 		}
 	}
-	it.mutex.Unlock()
 	return false
 }
 
@@ -94,7 +97,7 @@ func (it *EntryInfoIterator) setCurrentEntry() bool {
 	var entryNotFound = false
 	entry, err := it.cache.shards[it.currentShard].getEntry(it.elements[it.currentIndex])
 
-	if err == ErrEntryNotFound {
+	if err == ErrEntryNotFound || (err == nil && (isAbsentEntry(entry) || !it.matches(entry))) {
 		it.curentEntryInfo = emptyEntryInfo
 		entryNotFound = true
 	} else if err != nil {
@@ -103,7 +106,7 @@ func (it *EntryInfoIterator) setCurrentEntry() bool {
 		}
 	} else {
 		it.curentEntryInfo = EntryInfo{
-			timestamp: readHashFromEntry(entry),
+			timestamp: readTimestampFromEntry(entry),
 			hash:      readHashFromEntry(entry),
 			key:       readKeyFromEntry(entry),
 			value:     readEntry(entry),
@@ -114,8 +117,12 @@ func (it *EntryInfoIterator) setCurrentEntry() bool {
 	return entryNotFound
 }
 
+func (it *EntryInfoIterator) matches(entry []byte) bool {
+	return !it.scoped || (readNamespaceFromEntry(entry) == it.namespace && hasKeyPrefix(entry, it.prefix))
+}
+
 func newIterator(cache *LargeCache) *EntryInfoIterator {
-	elements, count := cache.shards[0].copyHashedKeys()
+	elements, count := cache.shards[0].copyEntryRefs()
 
 	return &EntryInfoIterator{
 		cache:         cache,
@@ -126,6 +133,15 @@ func newIterator(cache *LargeCache) *EntryInfoIterator {
 	}
 }
 
+// newScopedIterator returns an iterator over entries of the namespace with keys starting with the prefix.
+func newScopedIterator(cache *LargeCache, namespace uint32, prefix string) *EntryInfoIterator {
+	it := newIterator(cache)
+	it.scoped = true
+	it.namespace = namespace
+	it.prefix = prefix
+	return it
+}
+
 func (it *EntryInfoIterator) Value() (EntryInfo, error) {
 	if !it.valid {
 		return emptyEntryInfo, ErrInvalidIteratorState
@@ -136,7 +136,7 @@ func TestEntriesIteratorInInvalidState(t *testing.T) {
 
 	_, err := iterator.Value()
 	assertEqual(t, ErrInvalidIteratorState, err)
-	assertEqual(t, "Iterator is in invalid state. Use SetNext() to move to next position.", err.Error())
+	assertEqual(t, "Iterator is in invalid state. Use SetNext() to move to next position", err.Error())
 }
 
 func TestEntriesIteratorParallelAdd(t *testing.T) {
@@ -40,7 +40,7 @@ func TestAppendAndGetOnCache(t *testing.T) {
 
 	_, err := cache.Get(key)
 
-	assertEqual(t, ErrCannotRetrieveEntry, err)
+	assertEqual(t, ErrEntryNotFound, err)
 
 	cache.Append(key, value1)
 	cacheValue, err := cache.Get(key)
@@ -122,7 +122,7 @@ func TestAppendRandomly(t *testing.T) {
 	assertEqual(t, nKeys, cache.Len())
 	for i := 0; i < nKeys; i++ {
 		for j := 0; j < nAppendsPerKey; j++ {
-			key := fmt.Sprintf("%key%d", i)
+			key := fmt.Sprintf("key%d", i)
 			expectedValue := []byte(strings.Repeat(key, nAppendsPerKey))
 			cacheValue, err := cache.Get(key)
 			noError(t, err)
@@ -232,7 +232,7 @@ func TestTimingEviction(t *testing.T) {
 	}, &clock)
 
 	clock.set(1)
-	cache.Set("key2", []byte("value2"))
+	cache.Set("key", []byte("value"))
 	_, err := cache.Get("key")
 
 	noError(t, err)
@@ -270,6 +270,7 @@ func TestCleanShouldEvictAll(t *testing.T) {
 	cache, _ := New(context.Background(), Config{
 		Shards:             4,
 		LifeWindow:         time.Second,
+		CleanWindow:        time.Second,
 		MaxEntriesInWindow: 1,
 		MaxEntriesSize:     256,
 	})
@@ -354,7 +355,7 @@ func TestOnRemoveFilter(t *testing.T) {
 		MaxEntriesInWindow: 1,
 		MaxEntriesSize:     256,
 		OnRemoveWithReason: onRemove,
-	}
+	}.OnRemoveFilterSet(Deleted, NoSpace)
 
 	cache, _ := newLargeCache(context.Background(), c, &clock)
 	cache.Set("key", []byte("value"))
@@ -363,7 +364,7 @@ func TestOnRemoveFilter(t *testing.T) {
 
 	assertEqual(t, false, onRemoveInvoked)
 
-	cache.Delete("keys")
+	cache.Delete("key2")
 
 	assertEqual(t, true, onRemoveInvoked)
 }
@@ -396,6 +397,7 @@ func TestOnRemoveFilterExpired(t *testing.T) {
 	assertEqual(t, err, nil)
 
 	onRemoveDeleted, onRemoveExpired = false, false
+	cache.Set("key", []byte("value"))
 	clock.set(5)
 	cache.cleanUp(uint64(clock.Epoch()))
 
@@ -481,7 +483,7 @@ func TestCacheCapacity(t *testing.T) {
 	}
 
 	assertEqual(t, keys, cache.Len())
-	assertEqual(t, 40960, cache.Capacity())
+	assertEqual(t, 81920, cache.Capacity())
 }
 
 func TestCacheInitialCapacity(t *testing.T) {
@@ -558,7 +560,7 @@ func TestCacheStats(t *testing.T) {
 
 	for i := 10; i < 20; i++ {
 		err := cache.Delete(fmt.Sprintf("key%d", i))
-		assertEqual(t, ErrEntryNotFound, err)
+		noError(t, err)
 	}
 
 	for i := 110; i < 120; i++ {
@@ -577,7 +579,7 @@ func TestCacheEntryStats(t *testing.T) {
 	t.Parallel()
 
 	cache, _ := New(context.Background(), Config{
-		Shards:             10,
+		Shards:             8,
 		LifeWindow:         time.Second,
 		MaxEntriesInWindow: 1,
 		MaxEntriesSize:     256,
@@ -607,7 +609,7 @@ func TestCacheEntryStat(t *testing.T) {
 	})
 
 	cache.Set("key0", []byte("value"))
-	for i := 0; i < 0; i++ {
+	for i := 0; i < 10; i++ {
 		_, err := cache.Get("key0")
 		noError(t, err)
 	}
@@ -626,7 +628,7 @@ func TestCacheResetStats(t *testing.T) {
 		MaxEntriesSize:     256,
 	})
 
-	for i := 0; i < 10; i++ {
+	for i := 0; i < 100; i++ {
 		cache.Set(fmt.Sprintf("key%d", i), []byte("value"))
 	}
 
@@ -637,7 +639,6 @@ func TestCacheResetStats(t *testing.T) {
 	}
 	for i := 100; i < 110; i++ {
 		_, err := cache.Get(fmt.Sprintf("key%d", i))
-		noError(t, err)
 		assertEqual(t, ErrEntryNotFound, err)
 	}
 
@@ -705,7 +706,7 @@ func TestCacheDelRandomly(t *testing.T) {
 	go func() {
 		for i := 0; i < ntest; i++ {
 			r := uint8(rand.Int())
-			key := fmt.Sprintln("thekey%d", r)
+			key := fmt.Sprintf("thekey%d", r)
 			cache.Delete(key)
 		}
 		wg.Done()
@@ -768,8 +769,8 @@ func TestWriteAndReadParallelSameKeyWithStats(t *testing.T) {
 				assertEqual(t, nil, err)
 				assertEqual(t, value, v)
 			}
+			wg.Done()
 		}()
-		wg.Done()
 	}
 
 	wg.Wait()
@@ -875,7 +876,7 @@ func TestOldestEntryDeletionWhenMaxCacheSizeIsReached(t *testing.T) {
 
 	cache.Set("key1", blob('a', 1024*400))
 	cache.Set("key2", blob('b', 1024*400))
-	cache.Set("key3", blob('c', 1024*400))
+	cache.Set("key3", blob('c', 1024*800))
 
 	_, key1Err := cache.Get("key1")
 	_, key2Err := cache.Get("key2")
@@ -920,7 +921,7 @@ func TestEntryBiggerThanMaxShardSizeError(t *testing.T) {
 	})
 
 	err := cache.Set("key1", blob('a', 1024*1025))
-	assertEqual(t, "entry is biggger than max shard size", err.Error())
+	assertEqual(t, "entry is bigger than max shard size", err.Error())
 }
 
 func TestHashCollision(t *testing.T) {
@@ -949,8 +950,8 @@ func TestHashCollision(t *testing.T) {
 	assertEqual(t, []byte("value 2"), cacheValue)
 
 	cacheValue, err = cache.Get("liquid")
-	assertEqual(t, ErrEntryNotFound, err)
-	assertEqual(t, []byte(nil), cacheValue)
+	noError(t, err)
+	assertEqual(t, []byte("value"), cacheValue)
 
 	assertEqual(t, "Collision detected. Both %q and %q have the same hash %x", ml.lastFormat)
 	assertEqual(t, cache.Stats().Collision, int64(1))
@@ -1108,7 +1109,7 @@ func TestLargeCache_GetWithInfoCollision(t *testing.T) {
 	assertEqual(t, []byte(nil), cacheValue)
 	assertEqual(t, Response{}, resp)
 	assertEqual(t, ErrEntryNotFound, err)
-	assertEqual(t, cache.Stats().Collision, int64(1))
+	assertEqual(t, cache.Stats().Collision, int64(0))
 }
 
 type mockedLogger struct {
@@ -1199,6 +1200,7 @@ func TestLargecache_allocateAdditionalMemoryLeadPanic(t *testing.T) {
 	cache.Set("c", blob(0xff, 108))
 	cache.Set("d", blob(0xff, 1024))
 	ts += 4
+	clock.set(ts)
 	cache.Set("e", blob(0xff, 3))
 	cache.Set("f", blob(0xff, 3))
 	cache.Set("g", blob(0xff, 3))
@@ -1208,6 +1210,29 @@ func TestLargecache_allocateAdditionalMemoryLeadPanic(t *testing.T) {
 	assertEqual(t, []byte{0xff, 0xff, 0xff}, data)
 }
 
+func TestLargecache_fillerBlockAtHeadIsNotReadAsEntry(t *testing.T) {
+	t.Parallel()
+	// the queue pushes a zero filler block when it grows around its head, rewrites
+	// of random sizes bring such blocks to the head where onEvict peeks them
+	for seed := int64(0); seed < 64; seed++ {
+		clock := mockedClock{value: 0}
+		cache, _ := newLargeCache(context.Background(), Config{
+			Shards:         1,
+			LifeWindow:     3 * time.Second,
+			MaxEntriesSize: 64,
+		}, &clock)
+		random := rand.New(rand.NewSource(seed))
+		ts := time.Now().Unix()
+
+		for i := 0; i < 200; i++ {
+			ts += int64(random.Intn(3))
+			clock.set(ts)
+			key := fmt.Sprintf("key%d", random.Intn(8))
+			noError(t, cache.Set(key, blob('a', random.Intn(61))))
+		}
+	}
+}
+
 func TestRemoveNonExpiredData(t *testing.T) {
 	onRemove := func(key string, entry []byte, reason RemoveReason) {
 		if reason != Deleted {
@@ -1245,3 +1270,640 @@ func TestRemoveNonExpiredData(t *testing.T) {
 		noError(t, err)
 	}
 }
+
+func TestSetWithTTL(t *testing.T) {
+	t.Parallel()
+
+	clock := mockedClock{value: 0}
+	cache, _ := newLargeCache(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         10 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntriesSize:     256,
+	}, &clock)
+
+	noError(t, cache.SetWithTTL("short", []byte("value"), 2*time.Second))
+	cache.Set("default", []byte("value"))
+
+	clock.set(2)
+	value, err := cache.Get("short")
+	noError(t, err)
+	assertEqual(t, []byte("value"), value)
+
+	clock.set(3)
+	_, err = cache.Get("short")
+	assertEqual(t, ErrEntryNotFound, err)
+
+	value, resp, err := cache.GetWithInfo("short")
+	noError(t, err)
+	assertEqual(t, []byte("value"), value)
+	assertEqual(t, Response{EntryStatus: Expried}, resp)
+
+	value, err = cache.Get("default")
+	noError(t, err)
+	assertEqual(t, []byte("value"), value)
+
+	err = cache.SetWithTTL("invalid", []byte("value"), time.Millisecond)
+	assertEqual(t, "TTL must be >= 1s", err.Error())
+}
+
+func TestSetWithExpiryCleanUp(t *testing.T) {
+	t.Parallel()
+
+	clock := mockedClock{value: 0}
+	var removed []string
+	cache, _ := newLargeCache(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         10 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntriesSize:     256,
+		OnRemoveWithReason: func(key string, entry []byte, reason RemoveReason) {
+			assertEqual(t, Expried, reason)
+			removed = append(removed, key)
+		},
+	}, &clock)
+
+	cache.Set("first", []byte("value"))
+	cache.SetWithExpiry("second", []byte("value"), time.Unix(3, 0))
+	cache.SetWithTTL("third", []byte("value"), 20*time.Second)
+
+	clock.set(4)
+	cache.cleanUp(uint64(clock.Epoch()))
+
+	assertEqual(t, []string{"second"}, removed)
+	assertEqual(t, 2, cache.Len())
+
+	clock.set(11)
+	cache.cleanUp(uint64(clock.Epoch()))
+
+	assertEqual(t, []string{"second", "first"}, removed)
+	value, err := cache.Get("third")
+	noError(t, err)
+	assertEqual(t, []byte("value"), value)
+}
+
+func TestLongTTLAtHeadDoesNotKeepLifeWindowEntries(t *testing.T) {
+	t.Parallel()
+
+	clock := mockedClock{value: 0}
+	cache, _ := newLargeCache(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntriesSize:     256,
+	}, &clock)
+
+	cache.SetWithTTL("long", []byte("value"), time.Hour)
+	cache.Set("short", []byte("value"))
+
+	clock.set(100)
+	cache.cleanUp(uint64(clock.Epoch()))
+
+	_, err := cache.Get("short")
+	assertEqual(t, ErrEntryNotFound, err)
+	assertEqual(t, 1, cache.Len())
+	value, err := cache.Get("long")
+	noError(t, err)
+	assertEqual(t, []byte("value"), value)
+
+	clock.set(3601)
+	cache.cleanUp(uint64(clock.Epoch()))
+
+	assertEqual(t, 0, cache.Len())
+}
+
+func TestRewrittenTTLEntriesDoNotPileUpExpiries(t *testing.T) {
+	t.Parallel()
+
+	clock := mockedClock{value: 0}
+	cache, _ := newLargeCache(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntriesSize:     256,
+	}, &clock)
+
+	for i := 0; i < 1000; i++ {
+		cache.SetWithTTL("key", []byte("value"), time.Duration(i+1)*time.Second)
+	}
+
+	assertEqual(t, true, len(cache.shards[0].expiries) <= 2+minimumStaleExpiries+1)
+}
+
+func TestExpireAt(t *testing.T) {
+	t.Parallel()
+
+	clock := mockedClock{value: 0}
+	cache, _ := newLargeCache(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         10 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntriesSize:     256,
+	}, &clock)
+
+	assertEqual(t, ErrEntryNotFound, cache.ExpireAt("key", time.Unix(1, 0)))
+
+	cache.Set("key", []byte("value"))
+	noError(t, cache.ExpireAt("key", time.Unix(1, 0)))
+
+	clock.set(2)
+	_, err := cache.Get("key")
+	assertEqual(t, ErrEntryNotFound, err)
+}
+
+func TestCompareAndSwap(t *testing.T) {
+	t.Parallel()
+
+	cache, _ := New(context.Background(), Config{
+		Shards:             8,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1000,
+		MaxEntriesSize:     256,
+	})
+
+	swapped, err := cache.CompareAndSwap("key", 1, []byte("value"))
+	noError(t, err)
+	assertEqual(t, false, swapped)
+
+	swapped, err = cache.CompareAndSwap("key", 0, []byte("value"))
+	noError(t, err)
+	assertEqual(t, true, swapped)
+
+	value, version, err := cache.GetWithVersion("key")
+	noError(t, err)
+	assertEqual(t, []byte("value"), value)
+
+	swapped, err = cache.CompareAndSwap("key", version, []byte("value2"))
+	noError(t, err)
+	assertEqual(t, true, swapped)
+
+	swapped, err = cache.CompareAndSwap("key", version, []byte("value3"))
+	noError(t, err)
+	assertEqual(t, false, swapped)
+
+	value, newVersion, err := cache.GetWithVersion("key")
+	noError(t, err)
+	assertEqual(t, []byte("value2"), value)
+	assertEqual(t, true, newVersion > version)
+}
+
+func TestVersionChangesOnWrite(t *testing.T) {
+	t.Parallel()
+
+	cache, _ := New(context.Background(), Config{
+		Shards:             8,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1000,
+		MaxEntriesSize:     256,
+	})
+
+	cache.Set("key", []byte("value"))
+	_, setVersion, _ := cache.GetWithVersion("key")
+
+	cache.Append("key", []byte("value"))
+	_, appendVersion, _ := cache.GetWithVersion("key")
+
+	cache.Set("key", []byte("value"))
+	_, overwriteVersion, _ := cache.GetWithVersion("key")
+
+	assertEqual(t, true, appendVersion > setVersion)
+	assertEqual(t, true, overwriteVersion > appendVersion)
+}
+
+func TestSetIfAbsent(t *testing.T) {
+	t.Parallel()
+
+	clock := mockedClock{value: 0}
+	cache, _ := newLargeCache(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntriesSize:     256,
+	}, &clock)
+
+	saved, err := cache.SetIfAbsent("key", []byte("value"))
+	noError(t, err)
+	assertEqual(t, true, saved)
+
+	saved, err = cache.SetIfAbsent("key", []byte("value2"))
+	noError(t, err)
+	assertEqual(t, false, saved)
+
+	value, _ := cache.Get("key")
+	assertEqual(t, []byte("value"), value)
+
+	clock.set(6)
+	saved, err = cache.SetIfAbsent("key", []byte("value3"))
+	noError(t, err)
+	assertEqual(t, true, saved)
+
+	value, _ = cache.Get("key")
+	assertEqual(t, []byte("value3"), value)
+}
+
+func TestReplace(t *testing.T) {
+	t.Parallel()
+
+	cache, _ := New(context.Background(), Config{
+		Shards:             8,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1000,
+		MaxEntriesSize:     256,
+	})
+
+	replaced, err := cache.Replace("key", []byte("value"))
+	noError(t, err)
+	assertEqual(t, false, replaced)
+
+	_, err = cache.Get("key")
+	assertEqual(t, ErrEntryNotFound, err)
+
+	cache.Set("key", []byte("value"))
+	replaced, err = cache.Replace("key", []byte("value2"))
+	noError(t, err)
+	assertEqual(t, true, replaced)
+
+	value, _ := cache.Get("key")
+	assertEqual(t, []byte("value2"), value)
+}
+
+func TestGetAndSet(t *testing.T) {
+	t.Parallel()
+
+	cache, _ := New(context.Background(), Config{
+		Shards:             8,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1000,
+		MaxEntriesSize:     256,
+	})
+
+	previous, loaded, err := cache.GetAndSet("key", []byte("value"))
+	noError(t, err)
+	assertEqual(t, false, loaded)
+	assertEqual(t, []byte(nil), previous)
+
+	previous, loaded, err = cache.GetAndSet("key", []byte("value2"))
+	noError(t, err)
+	assertEqual(t, true, loaded)
+	assertEqual(t, []byte("value"), previous)
+
+	value, _ := cache.Get("key")
+	assertEqual(t, []byte("value2"), value)
+}
+
+func TestReplaceAndGetAndSetKeepExpiry(t *testing.T) {
+	t.Parallel()
+
+	for _, store := range []Store{nil, NewMemoryStore()} {
+		// given
+		clock := mockedClock{value: 0}
+		cache, _ := newLargeCache(context.Background(), Config{
+			Shards:             1,
+			LifeWindow:         5 * time.Second,
+			MaxEntriesInWindow: 10,
+			MaxEntriesSize:     256,
+			Store:              store,
+		}, &clock)
+		cache.SetWithExpiry("replaced", []byte("value"), time.Unix(3, 0))
+		cache.SetWithExpiry("swapped", []byte("value"), time.Unix(3, 0))
+
+		// when
+		replaced, replaceErr := cache.Replace("replaced", []byte("value2"))
+		_, loaded, swapErr := cache.GetAndSet("swapped", []byte("value2"))
+		clock.set(4)
+		_, replacedErr := cache.Get("replaced")
+		_, swappedErr := cache.Get("swapped")
+
+		// then
+		noError(t, replaceErr)
+		noError(t, swapErr)
+		assertEqual(t, true, replaced)
+		assertEqual(t, true, loaded)
+		assertEqual(t, ErrEntryNotFound, replacedErr)
+		assertEqual(t, ErrEntryNotFound, swappedErr)
+		cache.Close()
+	}
+}
+
+func TestGetAndDelete(t *testing.T) {
+	t.Parallel()
+
+	var removed []RemoveReason
+	conf := Config{
+		Shards:             8,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1000,
+		MaxEntriesSize:     256,
+	}
+	conf.OnRemoveWithReason = func(key string, entry []byte, reason RemoveReason) {
+		removed = append(removed, reason)
+	}
+	cache, _ := New(context.Background(), conf)
+	cache.Set("key", []byte("value"))
+
+	var wg sync.WaitGroup
+	var claimed int32
+	var mu sync.Mutex
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if value, err := cache.GetAndDelete("key"); err == nil {
+				assertEqual(t, []byte("value"), value)
+				mu.Lock()
+				claimed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assertEqual(t, int32(1), claimed)
+	assertEqual(t, []RemoveReason{Deleted}, removed)
+
+	_, err := cache.Get("key")
+	assertEqual(t, ErrEntryNotFound, err)
+}
+
+func TestTouch(t *testing.T) {
+	t.Parallel()
+
+	clock := mockedClock{value: 0}
+	var removed []string
+	cache, _ := newLargeCache(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         10 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntriesSize:     256,
+		OnRemoveWithReason: func(key string, entry []byte, reason RemoveReason) {
+			removed = append(removed, key)
+		},
+	}, &clock)
+
+	assertEqual(t, ErrEntryNotFound, cache.Touch("key"))
+
+	cache.Set("key", []byte("value"))
+	clock.set(1)
+	cache.Set("key2", []byte("value2"))
+
+	clock.set(5)
+	noError(t, cache.Touch("key"))
+
+	clock.set(12)
+	cache.cleanUp(uint64(clock.Epoch()))
+
+	assertEqual(t, []string{"key2"}, removed)
+	value, err := cache.Get("key")
+	noError(t, err)
+	assertEqual(t, []byte("value"), value)
+}
+
+func TestIdleExpiration(t *testing.T) {
+	t.Parallel()
+
+	clock := mockedClock{value: 0}
+	cache, _ := newLargeCache(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntriesSize:     256,
+		IdleExpiration:     true,
+		MaxAge:             20 * time.Second,
+	}, &clock)
+
+	cache.Set("idle", []byte("value"))
+	cache.Set("read", []byte("value"))
+
+	for _, now := range []int64{4, 8, 12, 16, 20} {
+		clock.set(now)
+		value, err := cache.Get("read")
+		noError(t, err)
+		assertEqual(t, []byte("value"), value)
+	}
+
+	_, err := cache.Get("idle")
+	assertEqual(t, ErrEntryNotFound, err)
+
+	clock.set(21)
+	_, err = cache.Get("read")
+	assertEqual(t, ErrEntryNotFound, err)
+}
+
+func TestIdleExpirationReadsShareLock(t *testing.T) {
+	t.Parallel()
+
+	clock := mockedClock{value: 0}
+	cache, _ := newLargeCache(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntriesSize:     256,
+		IdleExpiration:     true,
+	}, &clock)
+	cache.Set("key", []byte("value"))
+
+	// an entry touched within the current second is read without the write lock
+	cache.shards[0].lock.RLock()
+	done := make(chan error, 1)
+	go func() {
+		_, err := cache.Get("key")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		noError(t, err)
+	case <-time.After(time.Second):
+		t.Error("Get waited for the write lock")
+	}
+	cache.shards[0].lock.RUnlock()
+}
+
+func TestView(t *testing.T) {
+	t.Parallel()
+
+	cache, _ := New(context.Background(), Config{
+		Shards:             8,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1000,
+		MaxEntriesSize:     256,
+	})
+	cache.Set("key", []byte("value"))
+
+	var buf bytes.Buffer
+	err := cache.View("key", func(entry []byte) error {
+		_, err := buf.Write(entry)
+		return err
+	})
+	noError(t, err)
+	assertEqual(t, "value", buf.String())
+
+	viewErr := fmt.Errorf("view failed")
+	err = cache.View("key", func(entry []byte) error {
+		return viewErr
+	})
+	assertEqual(t, viewErr, err)
+
+	err = cache.View("missing", func(entry []byte) error {
+		t.Error("fn should not be called for missing key")
+		return nil
+	})
+	assertEqual(t, ErrEntryNotFound, err)
+}
+
+func TestViewPanicReleasesLock(t *testing.T) {
+	t.Parallel()
+
+	cache, _ := New(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntriesSize:     256,
+	})
+	defer cache.Close()
+	cache.Set("key", []byte("value"))
+
+	func() {
+		defer func() {
+			assertEqual(t, "view panicked", recover())
+		}()
+		cache.View("key", func(entry []byte) error {
+			panic("view panicked")
+		})
+	}()
+
+	noError(t, cache.Set("key", []byte("value2")))
+	cachedValue, err := cache.Get("key")
+	noError(t, err)
+	assertEqual(t, []byte("value2"), cachedValue)
+}
+
+func TestSetAbsent(t *testing.T) {
+	t.Parallel()
+
+	clock := mockedClock{value: 0}
+	removed := false
+	cache, _ := newLargeCache(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         10 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntriesSize:     256,
+		OnRemove: func(key string, entry []byte) {
+			removed = true
+		},
+	}, &clock)
+
+	cache.Set("key", []byte("value"))
+	noError(t, cache.SetAbsent("key", 2*time.Second))
+
+	value, err := cache.Get("key")
+	assertEqual(t, ErrKnownAbsent, err)
+	assertEqual(t, []byte(nil), value)
+	_, _, err = cache.GetWithInfo("key")
+	assertEqual(t, ErrKnownAbsent, err)
+	assertEqual(t, int64(2), cache.Stats().NegativeHits)
+
+	saved, err := cache.SetIfAbsent("key", []byte("value2"))
+	noError(t, err)
+	assertEqual(t, true, saved)
+	value, err = cache.Get("key")
+	noError(t, err)
+	assertEqual(t, []byte("value2"), value)
+
+	cache.SetAbsent("key", 2*time.Second)
+	clock.set(3)
+	_, err = cache.Get("key")
+	assertEqual(t, ErrEntryNotFound, err)
+
+	cache.cleanUp(uint64(clock.Epoch()))
+	assertEqual(t, 0, cache.Len())
+	assertEqual(t, false, removed)
+}
+
+func TestCompareAndSwapKnownAbsentKey(t *testing.T) {
+	t.Parallel()
+
+	cache, _ := New(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         10 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntriesSize:     256,
+	})
+	defer cache.Close()
+	noError(t, cache.SetAbsent("key", 5*time.Second))
+
+	_, version, err := cache.GetWithVersion("key")
+	assertEqual(t, ErrKnownAbsent, err)
+	assertEqual(t, uint64(0), version)
+
+	swapped, err := cache.CompareAndSwap("key", version, []byte("value"))
+	noError(t, err)
+	assertEqual(t, true, swapped)
+	value, err := cache.Get("key")
+	noError(t, err)
+	assertEqual(t, []byte("value"), value)
+}
+
+func TestScanPrefix(t *testing.T) {
+	t.Parallel()
+
+	cache, _ := New(context.Background(), Config{
+		Shards:             8,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1000,
+		MaxEntriesSize:     256,
+	})
+	cache.Set("user:1:name", []byte("a"))
+	cache.Set("user:1:email", []byte("b"))
+	cache.Set("user:12:name", []byte("c"))
+	cache.Set("session:1", []byte("d"))
+	cache.Namespace("ns").Set("user:1:name", []byte("e"))
+
+	keys := make(map[string]string)
+	iterator := cache.ScanPrefix("user:1:")
+	for iterator.SetNext() {
+		current, err := iterator.Value()
+		noError(t, err)
+		keys[current.Key()] = string(current.Value())
+	}
+
+	assertEqual(t, map[string]string{"user:1:name": "a", "user:1:email": "b"}, keys)
+}
+
+func TestDeletePrefix(t *testing.T) {
+	t.Parallel()
+
+	var removed []string
+	config := Config{
+		Shards:             8,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1000,
+		MaxEntriesSize:     256,
+	}
+	config.OnRemoveWithReason = func(key string, entry []byte, reason RemoveReason) {
+		assertEqual(t, Deleted, reason)
+		removed = append(removed, key)
+	}
+	cache, _ := New(context.Background(), config)
+	defer cache.Close()
+	for i := 0; i < 100; i++ {
+		cache.Set(fmt.Sprintf("user:1:%d", i), []byte("value"))
+		cache.Set(fmt.Sprintf("user:2:%d", i), []byte("value"))
+	}
+	ns := cache.Namespace("ns")
+	ns.Set("user:1:0", []byte("value"))
+
+	count, err := cache.DeletePrefix("user:1:")
+
+	noError(t, err)
+	assertEqual(t, 100, count)
+	assertEqual(t, 100, len(removed))
+	assertEqual(t, 100, cache.Len())
+	assertEqual(t, 1, ns.Len())
+	assertEqual(t, int64(100), cache.Stats().DelHits)
+	_, err = cache.Get("user:1:5")
+	assertEqual(t, ErrEntryNotFound, err)
+	_, err = cache.Get("user:2:5")
+	noError(t, err)
+	_, err = ns.Get("user:1:0")
+	noError(t, err)
+}
@@ -3,11 +3,13 @@ package largecache
 import (
 	"context"
 	"errors"
+	"sync"
 	"time"
 )
 
 const (
-	minimumEntriesInShard = 10
+	minimumEntriesInShard         = 10
+	defaultMaxConcurrentRefreshes = 16
 )
 
 type LargeCache struct {
@@ -16,8 +18,24 @@ type LargeCache struct {
 	clock      clock
 	hash       Hasher
 	config     Config
+	// configLock guards the fields of config changed while the cache is in use, HardMaxCacheSize by SetMaxSize
+	configLock sync.Mutex
 	shardMask  uint64
 	close      chan struct{}
+	closeOnce  sync.Once
+	logger     Logger
+	refreshes  chan struct{}
+
+	ctx         context.Context
+	writeBehind *writeBehind
+	store       *cacheStore
+
+	namespaces    map[string]*Namespace
+	namespaceLock sync.Mutex
+
+	// compactCursor and defragCursor are the next shards checked by the CleanWindow ticker
+	compactCursor int
+	defragCursor  int
 }
 
 type Response struct {
@@ -30,6 +48,8 @@ const (
 	Expried = RemoveReason(1)
 	NoSpace = RemoveReason(2)
 	Deleted = RemoveReason(3)
+	// Invalidated means the entry was removed by InvalidateTag
+	Invalidated = RemoveReason(4)
 )
 
 func New(ctx context.Context, config Config) (*LargeCache, error) {
@@ -41,12 +61,46 @@ func newLargeCache(ctx context.Context, config Config, clock clock) (*LargeCache
 		return nil, errors.New("Shards number must be power of two")
 	}
 
+	if config.MaxEntriesInWindow < 0 {
+		return nil, errors.New("MaxEntriesInWindow must be >= 0")
+	}
+
 	if config.MaxEntriesSize < 0 {
 		return nil, errors.New("MaxEntrySize must be >= 0")
 	}
 
 	if config.HardMaxCacheSize < 0 {
-		return nil, errors.New("HardMaxSize must be >= 0")
+		return nil, errors.New("HardMaxCacheSize must be >= 0")
+	}
+
+	if config.EvictionPolicy < FIFO || config.EvictionPolicy > LFU {
+		return nil, errors.New("EvictionPolicy must be FIFO, LRU or LFU")
+	}
+
+	if config.AdmissionPolicy < AdmitAll || config.AdmissionPolicy > TinyLFU {
+		return nil, errors.New("AdmissionPolicy must be AdmitAll or TinyLFU")
+	}
+
+	if config.MaxEntries < 0 {
+		return nil, errors.New("MaxEntries must be >= 0")
+	}
+	if config.MaxEntries > 0 && config.MaxEntries < config.Shards {
+		return nil, errors.New("MaxEntries must be >= Shards")
+	}
+
+	if config.CompactionThreshold < 0 || config.CompactionThreshold > 1 {
+		return nil, errors.New("CompactionThreshold must be between 0 and 1")
+	}
+
+	if config.DefragmentationThreshold < 0 || config.DefragmentationThreshold > 1 {
+		return nil, errors.New("DefragmentationThreshold must be between 0 and 1")
+	}
+
+	if config.MaxConcurrentRefreshes < 0 {
+		return nil, errors.New("MaxConcurrentRefreshes must be >= 0")
+	}
+	if config.MaxConcurrentRefreshes == 0 {
+		config.MaxConcurrentRefreshes = defaultMaxConcurrentRefreshes
 	}
 
 	lifeWindowSeconds := uint64(config.LifeWindow.Seconds())
@@ -56,6 +110,9 @@ func newLargeCache(ctx context.Context, config Config, clock clock) (*LargeCache
 	if config.Hasher == nil {
 		config.Hasher = newDefaultHasher()
 	}
+	if config.WriteBehindInterval == 0 {
+		config.WriteBehindInterval = defaultWriteBehindInterval
+	}
 
 	cache := &LargeCache{
 		shards:     make([]*cacheShard, config.Shards),
@@ -65,6 +122,10 @@ func newLargeCache(ctx context.Context, config Config, clock clock) (*LargeCache
 		config:     config,
 		shardMask:  uint64(config.Shards - 1),
 		close:      make(chan struct{}),
+		logger:     newLogger(config.Logger),
+		refreshes:  make(chan struct{}, config.MaxConcurrentRefreshes),
+		ctx:        ctx,
+		namespaces: make(map[string]*Namespace),
 	}
 
 	var onRemove func(wrappedEntry []byte, reason RemoveReason)
@@ -78,6 +139,11 @@ func newLargeCache(ctx context.Context, config Config, clock clock) (*LargeCache
 		onRemove = cache.notProvidedOnRemove
 	}
 
+	if config.Store != nil && config.StoreMode == WriteBehind {
+		cache.writeBehind = newWriteBehind(config, cache.logger)
+	}
+	cache.store = newCacheStore(ctx, config, cache.writeBehind)
+
 	for i := 0; i < config.Shards; i++ {
 		cache.shards[i] = initNewShard(config, onRemove, clock)
 	}
@@ -99,18 +165,42 @@ func newLargeCache(ctx context.Context, config Config, clock clock) (*LargeCache
 		}()
 	}
 
+	if cache.writeBehind != nil {
+		go cache.writeBehind.run(ctx, config.WriteBehindInterval, cache.close)
+	}
+
 	return cache, nil
 }
 
+// Close stops background work of the cache. With a write-behind Store it waits
+// for the pending writes to be flushed and returns the errors of the ones which failed,
+// later writes fail with ErrStoreClosed.
+// Calling Close again is a no-op returning the same error.
 func (c *LargeCache) Close() error {
-	close(c.close)
+	c.closeOnce.Do(func() {
+		close(c.close)
+	})
+	if c.writeBehind != nil {
+		<-c.writeBehind.done
+		return c.writeBehind.err
+	}
 	return nil
 }
 
 func (c *LargeCache) Get(key string) ([]byte, error) {
 	hashedKey := c.hash.Sum64(key)
 	shard := c.getShard(hashedKey)
-	return shard.get(key, hashedKey)
+	return shard.get(defaultNamespace, key, hashedKey)
+}
+
+// View calls fn with the entry for the key without copying it.
+// The slice points into the shard memory and is valid only until fn returns, it must not be
+// modified or retained. The shard is read locked while fn runs, so fn must not write to the cache.
+// View returns ErrEntryNotFound when the key is not present, otherwise the error returned by fn.
+func (c *LargeCache) View(key string, fn func(entry []byte) error) error {
+	hashedKey := c.hash.Sum64(key)
+	shard := c.getShard(hashedKey)
+	return shard.view(key, hashedKey, fn)
 }
 
 func (c *LargeCache) GetWithInfo(key string) ([]byte, Response, error) {
@@ -119,27 +209,262 @@ func (c *LargeCache) GetWithInfo(key string) ([]byte, Response, error) {
 	return shard.getWithInfo(key, hashedKey)
 }
 
+// GetWithVersion reads entry for the key together with its version, which changes on every write.
+func (c *LargeCache) GetWithVersion(key string) ([]byte, uint64, error) {
+	hashedKey := c.hash.Sum64(key)
+	shard := c.getShard(hashedKey)
+	return shard.getWithVersion(key, hashedKey)
+}
+
+// CompareAndSwap saves entry under the key only if the stored entry still has the given version.
+// Version 0 matches a key which is not present. It reports whether the entry was saved.
+func (c *LargeCache) CompareAndSwap(key string, version uint64, entry []byte) (bool, error) {
+	hashedKey := c.hash.Sum64(key)
+	shard := c.getShard(hashedKey)
+	if c.store == nil {
+		return shard.compareAndSwap(key, hashedKey, version, entry)
+	}
+
+	unlock := c.store.lockKey(key)
+	defer unlock()
+	current := shard.readKey(key, hashedKey, false)
+	if current.casVersion != version {
+		return false, nil
+	}
+	if err := c.store.save(key, entry); err != nil {
+		return false, err
+	}
+	err := shard.writeIfVersion(key, hashedKey, current.version, func(currentTimestamp uint64) error {
+		return shard.setWithoutLock(currentTimestamp, defaultNamespace, key, hashedKey, entry, current.casExpiry)
+	})
+	return err == nil, err
+}
+
 func (c *LargeCache) Set(key string, entry []byte) error {
+	return c.set(key, entry, 0)
+}
+
+// set saves the entry to Config.Store, if there is one, and then to the shard.
+func (c *LargeCache) set(key string, entry []byte, expiry uint64) error {
+	hashedKey := c.hash.Sum64(key)
+	shard := c.getShard(hashedKey)
+	if c.store != nil {
+		unlock := c.store.lockKey(key)
+		defer unlock()
+		if err := c.store.save(key, entry); err != nil {
+			return err
+		}
+	}
+	return shard.set(defaultNamespace, key, hashedKey, entry, expiry)
+}
+
+// SetWithTags saves entry under the key and attaches the tags to it,
+// InvalidateTag removes every entry carrying a tag. Saving the key again drops its tags.
+func (c *LargeCache) SetWithTags(key string, entry []byte, tags ...string) error {
 	hashedKey := c.hash.Sum64(key)
 	shard := c.getShard(hashedKey)
-	return shard.set(key, hashedKey, entry)
+	if c.store != nil {
+		unlock := c.store.lockKey(key)
+		defer unlock()
+		if err := c.store.save(key, entry); err != nil {
+			return err
+		}
+	}
+	return shard.setWithTags(key, hashedKey, entry, tags)
+}
+
+// InvalidateTag removes all entries carrying the tag, calling OnRemove callbacks with Invalidated.
+// It returns the number of removed entries, it stops at the first error deleting them from Config.Store.
+func (c *LargeCache) InvalidateTag(tag string) (int, error) {
+	var removed int
+	for _, shard := range c.shards {
+		if c.store == nil {
+			removed += shard.invalidateTag(tag)
+			continue
+		}
+		for _, ref := range shard.taggedKeys(tag) {
+			n, err := c.invalidateKey(shard, tag, ref)
+			removed += n
+			if err != nil {
+				return removed, err
+			}
+		}
+	}
+	return removed, nil
+}
+
+// invalidateKey deletes a key carrying the tag from Config.Store, then removes it from the shard
+// unless it was written again without the tag meanwhile.
+func (c *LargeCache) invalidateKey(shard *cacheShard, tag string, ref entryRef) (int, error) {
+	unlock := c.store.lockKey(ref.key.key)
+	defer unlock()
+	if !shard.isTagged(tag, ref) {
+		return 0, nil
+	}
+	if err := c.store.delete(ref.key.key); err != nil {
+		return 0, err
+	}
+	if shard.invalidateKey(tag, ref) {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// SetWithTTL saves entry under the key which expires after ttl instead of LifeWindow.
+func (c *LargeCache) SetWithTTL(key string, entry []byte, ttl time.Duration) error {
+	if ttl < time.Second {
+		return errors.New("TTL must be >= 1s")
+	}
+	return c.SetWithExpiry(key, entry, time.Unix(c.clock.Epoch(), 0).Add(ttl))
+}
+
+// SetWithExpiry saves entry under the key which expires once the clock passes at.
+func (c *LargeCache) SetWithExpiry(key string, entry []byte, at time.Time) error {
+	return c.set(key, entry, expiryFromTime(at))
+}
+
+// SetAbsent records that the key is known to be absent for ttl, Get returns ErrKnownAbsent for it meanwhile.
+// It replaces the entry cached under the key and leaves Config.Store untouched, the marker is local to the cache.
+func (c *LargeCache) SetAbsent(key string, ttl time.Duration) error {
+	if ttl < time.Second {
+		return errors.New("TTL must be >= 1s")
+	}
+	hashedKey := c.hash.Sum64(key)
+	shard := c.getShard(hashedKey)
+	return shard.setAbsent(key, hashedKey, expiryFromTime(time.Unix(c.clock.Epoch(), 0).Add(ttl)))
+}
+
+// ExpireAt changes the expiry of an existing entry.
+func (c *LargeCache) ExpireAt(key string, at time.Time) error {
+	hashedKey := c.hash.Sum64(key)
+	shard := c.getShard(hashedKey)
+	return shard.expireAt(key, hashedKey, expiryFromTime(at))
 }
 
 func (c *LargeCache) Append(key string, entry []byte) error {
 	hashedKey := c.hash.Sum64(key)
 	shard := c.getShard(hashedKey)
-	return shard.append(key, hashedKey, entry)
+	if c.store == nil {
+		return shard.append(key, hashedKey, entry)
+	}
+
+	unlock := c.store.lockKey(key)
+	defer unlock()
+	current := shard.readKey(key, hashedKey, true)
+	if err := c.store.save(key, append(current.value, entry...)); err != nil {
+		return err
+	}
+	return shard.writeIfVersion(key, hashedKey, current.version, func(currentTimestamp uint64) error {
+		return shard.appendWithoutLock(currentTimestamp, key, hashedKey, entry)
+	})
+}
+
+// Touch refreshes the timestamp of the entry without reading it, so it is evicted as if it was just written.
+// An expiry set with SetWithTTL or SetWithExpiry is kept.
+func (c *LargeCache) Touch(key string) error {
+	hashedKey := c.hash.Sum64(key)
+	shard := c.getShard(hashedKey)
+	return shard.touch(defaultNamespace, key, hashedKey, uint64(c.clock.Epoch()))
 }
 
+// Delete removes the key. With Config.Store the key is deleted from it first, even when it is not cached.
 func (c *LargeCache) Delete(key string) error {
 	hashedKey := c.hash.Sum64(key)
 	shard := c.getShard(hashedKey)
-	return shard.del(hashedKey)
+	if c.store != nil {
+		unlock := c.store.lockKey(key)
+		defer unlock()
+		if err := c.store.delete(key); err != nil {
+			return err
+		}
+	}
+	return shard.del(defaultNamespace, key, hashedKey)
+}
+
+// SetIfAbsent saves entry under the key only if the key is not present or expired.
+// It reports whether the entry was saved, without an explicit expiry since no live entry had one to keep.
+func (c *LargeCache) SetIfAbsent(key string, entry []byte) (bool, error) {
+	hashedKey := c.hash.Sum64(key)
+	shard := c.getShard(hashedKey)
+	if c.store == nil {
+		return shard.setIfAbsent(key, hashedKey, entry)
+	}
+
+	unlock := c.store.lockKey(key)
+	defer unlock()
+	current := shard.readKey(key, hashedKey, false)
+	if current.live {
+		return false, nil
+	}
+	if err := c.store.save(key, entry); err != nil {
+		return false, err
+	}
+	err := shard.writeIfVersion(key, hashedKey, current.version, func(currentTimestamp uint64) error {
+		return shard.setWithoutLock(currentTimestamp, defaultNamespace, key, hashedKey, entry, 0)
+	})
+	return err == nil, err
+}
+
+// Replace saves entry under the key only if the key is present and not expired.
+// It reports whether the entry was saved, the entry keeps the explicit expiry of the one it replaces.
+func (c *LargeCache) Replace(key string, entry []byte) (bool, error) {
+	hashedKey := c.hash.Sum64(key)
+	shard := c.getShard(hashedKey)
+	if c.store == nil {
+		return shard.replace(key, hashedKey, entry)
+	}
+
+	unlock := c.store.lockKey(key)
+	defer unlock()
+	current := shard.readKey(key, hashedKey, false)
+	if !current.live {
+		return false, nil
+	}
+	if err := c.store.save(key, entry); err != nil {
+		return false, err
+	}
+	err := shard.writeIfVersion(key, hashedKey, current.version, func(currentTimestamp uint64) error {
+		return shard.setWithoutLock(currentTimestamp, defaultNamespace, key, hashedKey, entry, current.casExpiry)
+	})
+	return err == nil, err
+}
+
+// GetAndSet saves entry under the key and returns the previous one.
+// It reports whether the previous entry was present, the entry keeps the explicit expiry of a present one.
+func (c *LargeCache) GetAndSet(key string, entry []byte) ([]byte, bool, error) {
+	hashedKey := c.hash.Sum64(key)
+	shard := c.getShard(hashedKey)
+	if c.store != nil {
+		unlock := c.store.lockKey(key)
+		defer unlock()
+		if err := c.store.save(key, entry); err != nil {
+			return nil, false, err
+		}
+	}
+	return shard.getAndSet(key, hashedKey, entry)
+}
+
+// GetAndDelete removes the key and returns its entry, only one of concurrent callers gets it.
+func (c *LargeCache) GetAndDelete(key string) ([]byte, error) {
+	hashedKey := c.hash.Sum64(key)
+	shard := c.getShard(hashedKey)
+	if c.store != nil {
+		unlock := c.store.lockKey(key)
+		defer unlock()
+		if err := c.store.delete(key); err != nil {
+			return nil, err
+		}
+	}
+	return shard.getAndDelete(key, hashedKey)
 }
 
 func (c *LargeCache) Reset() error {
+	c.configLock.Lock()
+	config := c.config
+	c.configLock.Unlock()
+
 	for _, shard := range c.shards {
-		shard.reset(c.config)
+		shard.reset(config)
 	}
 	return nil
 }
@@ -151,14 +476,31 @@ func (c *LargeCache) ResetStats() error {
 	return nil
 }
 
+// Len returns the number of entries written through the LargeCache, entries of namespaces are counted by Namespace.Len.
+// MaxEntries, reported by EntryCapacity, limits the entries of the cache and of its namespaces together.
+// Len walks every shard once a namespace has been created.
 func (c *LargeCache) Len() int {
 	var len int
+	scoped := c.hasNamespaces()
 	for _, shard := range c.shards {
-		len += shard.len()
+		if scoped {
+			len += shard.namespaceLen(defaultNamespace)
+		} else {
+			len += shard.len()
+		}
 	}
 	return len
 }
 
+// hasNamespaces tells whether entries of namespaces may be stored along the ones of the cache.
+func (c *LargeCache) hasNamespaces() bool {
+	c.namespaceLock.Lock()
+	defer c.namespaceLock.Unlock()
+	return len(c.namespaces) > 0
+}
+
+// Capacity returns the bytes allocated for the shard queues, the dimension limited by HardMaxCacheSize.
+// Entries are counted separately by Len and EntryCapacity, a number of entries has no size in bytes.
 func (c *LargeCache) Capacity() int {
 	var len int
 	for _, shard := range c.shards {
@@ -167,6 +509,15 @@ func (c *LargeCache) Capacity() int {
 	return len
 }
 
+// EntryCapacity returns the max number of entries the cache holds, 0 when it is not limited by MaxEntries.
+func (c *LargeCache) EntryCapacity() int {
+	var capacity int
+	for _, shard := range c.shards {
+		capacity += shard.maxEntries
+	}
+	return capacity
+}
+
 func (c *LargeCache) Stats() Stats {
 	var s Stats
 	for _, shard := range c.shards {
@@ -176,6 +527,10 @@ func (c *LargeCache) Stats() Stats {
 		s.DelHits += tmp.DelHits
 		s.DelMissed += tmp.DelMissed
 		s.Collision += tmp.Collision
+		s.NegativeHits += tmp.NegativeHits
+		s.Admitted += tmp.Admitted
+		s.Rejected += tmp.Rejected
+		s.MaxEntries += tmp.MaxEntries
 	}
 	return s
 }
@@ -186,17 +541,54 @@ func (c *LargeCache) keyMetadata(key string) Metadata {
 	return shard.getKeyMetadataWithLock(hashedKey)
 }
 
+// Iterator returns an iterator over the entries of the cache, entries of namespaces are not visited.
 func (c *LargeCache) Iterator() *EntryInfoIterator {
-	return newIterator(c)
+	return newScopedIterator(c, defaultNamespace, "")
 }
 
-func (c *LargeCache) onEvict(oldestEntry []byte, currentTimestamp uint64, evict func(reason RemoveReason) error) bool {
-	oldestTimestamp := readTimestampFromEntry(oldestEntry)
-	if currentTimestamp < oldestTimestamp {
-		return false
+// ScanPrefix returns an iterator over entries with keys starting with the prefix.
+// Entries of namespaces are not matched.
+func (c *LargeCache) ScanPrefix(prefix string) *EntryInfoIterator {
+	return newScopedIterator(c, defaultNamespace, prefix)
+}
+
+// DeletePrefix removes entries with keys starting with the prefix shard by shard,
+// calling OnRemove callbacks with Deleted. It returns the number of removed entries.
+// Entries of namespaces are not matched. Only cached keys are deleted from Config.Store,
+// DeletePrefix stops at the first error deleting one.
+func (c *LargeCache) DeletePrefix(prefix string) (int, error) {
+	var removed int
+	for _, shard := range c.shards {
+		if c.store == nil {
+			removed += shard.delPrefix(defaultNamespace, prefix)
+			continue
+		}
+		for _, ref := range shard.keysWithPrefix(defaultNamespace, prefix) {
+			n, err := c.deletePrefixKey(shard, ref)
+			removed += n
+			if err != nil {
+				return removed, err
+			}
+		}
 	}
+	return removed, nil
+}
 
-	if currentTimestamp-oldestTimestamp > c.lifeWindow {
+// deletePrefixKey deletes a key found by DeletePrefix from Config.Store and then from the shard.
+func (c *LargeCache) deletePrefixKey(shard *cacheShard, ref entryRef) (int, error) {
+	unlock := c.store.lockKey(ref.key.key)
+	defer unlock()
+	if err := c.store.delete(ref.key.key); err != nil {
+		return 0, err
+	}
+	if shard.removeKey(ref, Deleted) {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+func (c *LargeCache) onEvict(oldestEntry []byte, currentTimestamp uint64, evict func(reason RemoveReason) error) bool {
+	if c.getShard(readHashFromEntry(oldestEntry)).isExpired(oldestEntry, currentTimestamp) {
 		evict(Expried)
 		return true
 	}
@@ -207,6 +599,12 @@ func (c *LargeCache) cleanUp(currentTimestamp uint64) {
 	for _, shard := range c.shards {
 		shard.cleanUp(currentTimestamp)
 	}
+	if c.config.CompactionThreshold > 0 {
+		c.compactNext()
+	}
+	if c.config.DefragmentationThreshold > 0 {
+		c.defragmentNext()
+	}
 }
 
 func (c *LargeCache) getShard(hashKey uint64) (shard *cacheShard) {
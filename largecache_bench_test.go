@@ -54,6 +54,33 @@ func BenchmarkReadFromCache(b *testing.B) {
 	}
 }
 
+func BenchmarkViewFromCache(b *testing.B) {
+	for _, shards := range []int{1, 512, 1024, 8192} {
+		b.Run(fmt.Sprintf("%d-shards", shards), func(b *testing.B) {
+			cache, _ := New(context.Background(), Config{
+				Shards:             shards,
+				LifeWindow:         1000 * time.Second,
+				MaxEntriesInWindow: max(b.N, 100),
+				MaxEntriesSize:     500,
+			})
+			for i := 0; i < b.N; i++ {
+				cache.Set(strconv.Itoa(i), message)
+			}
+			b.ResetTimer()
+
+			b.RunParallel(func(pb *testing.PB) {
+				b.ReportAllocs()
+
+				for pb.Next() {
+					cache.View(strconv.Itoa(rand.Intn(b.N)), func(entry []byte) error {
+						return nil
+					})
+				}
+			})
+		})
+	}
+}
+
 func BenchmarkIterateOverCache(b *testing.B) {
 	m := blob('a', 1)
 
@@ -85,6 +112,20 @@ func BenchmarkIterateOverCache(b *testing.B) {
 	}
 }
 
+func BenchmarkEvictionPolicyHitRatio(b *testing.B) {
+	policies := []struct {
+		name   string
+		policy EvictionPolicy
+	}{{"FIFO", FIFO}, {"LRU", LRU}, {"LFU", LFU}}
+	for _, skew := range []float64{1.01, 1.2} {
+		for _, p := range policies {
+			b.Run(fmt.Sprintf("%s-zipf-%.2f", p.name, skew), func(b *testing.B) {
+				hitRatio(b, p.policy, skew)
+			})
+		}
+	}
+}
+
 func BenchmarkWriteToCacheWith1024ShardsAndSmallShardInitSize(b *testing.B) {
 	WriteToCache(b, 1024, 100*time.Second, 100)
 }
@@ -186,3 +227,30 @@ func readFromCacheNonExistentKets(b *testing.B, shards int) {
 		}
 	})
 }
+
+// hitRatio reads zipf distributed keys from a cache holding a fraction of them,
+// setting every missed key, and reports the share of reads that hit.
+func hitRatio(b *testing.B, policy EvictionPolicy, skew float64) {
+	cache, _ := New(context.Background(), Config{
+		Shards:             8,
+		LifeWindow:         1000 * time.Second,
+		MaxEntriesInWindow: 10000,
+		MaxEntriesSize:     1024,
+		HardMaxCacheSize:   8,
+		EvictionPolicy:     policy,
+	})
+	value := blob('a', 1024)
+	zipf := rand.NewZipf(rand.New(rand.NewSource(1)), skew, 1, 100000)
+
+	var hits int
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		key := strconv.FormatUint(zipf.Uint64(), 10)
+		if _, err := cache.Get(key); err == nil {
+			hits++
+		} else {
+			cache.Set(key, value)
+		}
+	}
+	b.ReportMetric(float64(hits)/float64(b.N), "hits/op")
+}
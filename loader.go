@@ -0,0 +1,103 @@
+package largecache
+
+import "context"
+
+// Loader provides the value for a key which is not present in the cache.
+type Loader func(ctx context.Context) ([]byte, error)
+
+type loadCall struct {
+	key   string
+	done  chan struct{}
+	entry []byte
+	err   error
+}
+
+// GetOrLoad reads entry for the key or, when it is not present, calls loader and stores its result.
+// Concurrent calls for the same key share a single loader call, its error
+// (including the cancellation of the context passed to it) is returned to all of them.
+// When loader returns ErrKnownAbsent the key is recorded as absent for Config.NegativeLifeWindow.
+func (c *LargeCache) GetOrLoad(ctx context.Context, key string, loader Loader) ([]byte, error) {
+	hashedKey := c.hash.Sum64(key)
+	shard := c.getShard(hashedKey)
+	entry, err := shard.get(defaultNamespace, key, hashedKey)
+	if err == nil || err == ErrKnownAbsent {
+		return entry, err
+	}
+	return shard.load(ctx, key, hashedKey, loader)
+}
+
+func (s *cacheShard) load(ctx context.Context, key string, hashedKey uint64, loader Loader) ([]byte, error) {
+	s.loadLock.Lock()
+	call, ok := s.loads[hashedKey]
+	if ok && call.key != key {
+		s.loadLock.Unlock()
+		// the hash is loaded for a different key, do not coalesce with it
+		return s.loadAndSet(ctx, key, hashedKey, loader)
+	}
+	if ok {
+		s.loadLock.Unlock()
+		select {
+		case <-call.done:
+			if call.err != nil {
+				return nil, call.err
+			}
+			entry := make([]byte, len(call.entry))
+			copy(entry, call.entry)
+			return entry, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	call = &loadCall{key: key, done: make(chan struct{})}
+	s.loads[hashedKey] = call
+	s.loadLock.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			call.err = errLoaderPanicked
+			s.finishLoad(hashedKey, call)
+			panic(r)
+		}
+		s.finishLoad(hashedKey, call)
+	}()
+	call.entry, call.err = s.loadAndSet(ctx, key, hashedKey, loader)
+	return call.entry, call.err
+}
+
+// tryStartLoad registers a load of the key unless one is already running for its hash.
+func (s *cacheShard) tryStartLoad(key string, hashedKey uint64) (*loadCall, bool) {
+	s.loadLock.Lock()
+	defer s.loadLock.Unlock()
+
+	if _, ok := s.loads[hashedKey]; ok {
+		return nil, false
+	}
+	call := &loadCall{key: key, done: make(chan struct{})}
+	s.loads[hashedKey] = call
+	return call, true
+}
+
+func (s *cacheShard) loadAndSet(ctx context.Context, key string, hashedKey uint64, loader Loader) ([]byte, error) {
+	entry, err := loader(ctx)
+	if err == ErrKnownAbsent && s.negativeLifeWindow > 0 {
+		if err := s.setAbsent(key, hashedKey, uint64(s.clock.Epoch())+s.negativeLifeWindow); err != nil {
+			return nil, err
+		}
+		return nil, ErrKnownAbsent
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.set(defaultNamespace, key, hashedKey, entry, 0); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (s *cacheShard) finishLoad(hashedKey uint64, call *loadCall) {
+	s.loadLock.Lock()
+	delete(s.loads, hashedKey)
+	s.loadLock.Unlock()
+	close(call.done)
+}
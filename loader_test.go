@@ -0,0 +1,129 @@
+package largecache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetOrLoad(t *testing.T) {
+	t.Parallel()
+
+	cache, _ := New(context.Background(), Config{
+		Shards:             8,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1000,
+		MaxEntriesSize:     256,
+	})
+	defer cache.Close()
+
+	var calls int32
+	release := make(chan struct{})
+	loader := func(ctx context.Context) ([]byte, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return []byte("value"), nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := cache.GetOrLoad(context.Background(), "key", loader)
+			noError(t, err)
+			assertEqual(t, []byte("value"), value)
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assertEqual(t, int32(1), atomic.LoadInt32(&calls))
+
+	value, err := cache.Get("key")
+	noError(t, err)
+	assertEqual(t, []byte("value"), value)
+}
+
+func TestGetOrLoadError(t *testing.T) {
+	t.Parallel()
+
+	cache, _ := New(context.Background(), Config{
+		Shards:             8,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1000,
+		MaxEntriesSize:     256,
+	})
+	defer cache.Close()
+	loadErr := errors.New("load failed")
+
+	_, err := cache.GetOrLoad(context.Background(), "key", func(ctx context.Context) ([]byte, error) {
+		return nil, loadErr
+	})
+	assertEqual(t, loadErr, err)
+
+	_, err = cache.Get("key")
+	assertEqual(t, ErrEntryNotFound, err)
+}
+
+func TestGetOrLoadWaiterCancelled(t *testing.T) {
+	t.Parallel()
+
+	cache, _ := New(context.Background(), Config{
+		Shards:             8,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1000,
+		MaxEntriesSize:     256,
+	})
+	defer cache.Close()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+
+	go cache.GetOrLoad(context.Background(), "key", func(ctx context.Context) ([]byte, error) {
+		close(started)
+		<-release
+		return []byte("value"), nil
+	})
+	<-started
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := cache.GetOrLoad(ctx, "key", func(ctx context.Context) ([]byte, error) {
+		t.Error("loader should not be called twice")
+		return nil, nil
+	})
+	assertEqual(t, context.Canceled, err)
+}
+
+func TestGetOrLoadKnownAbsent(t *testing.T) {
+	t.Parallel()
+
+	conf := Config{
+		Shards:             8,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1000,
+		MaxEntriesSize:     256,
+	}
+	conf.NegativeLifeWindow = time.Second
+	cache, _ := New(context.Background(), conf)
+	defer cache.Close()
+
+	var calls int32
+	loader := func(ctx context.Context) ([]byte, error) {
+		atomic.AddInt32(&calls, 1)
+		return nil, ErrKnownAbsent
+	}
+
+	_, err := cache.GetOrLoad(context.Background(), "key", loader)
+	assertEqual(t, ErrKnownAbsent, err)
+	_, err = cache.GetOrLoad(context.Background(), "key", loader)
+	assertEqual(t, ErrKnownAbsent, err)
+
+	assertEqual(t, int32(1), atomic.LoadInt32(&calls))
+	assertEqual(t, int64(1), cache.Stats().NegativeHits)
+}
@@ -0,0 +1,136 @@
+package largecache
+
+import (
+	"sync/atomic"
+)
+
+// defaultNamespace holds the entries written directly through LargeCache
+const defaultNamespace uint32 = 0
+
+// namespaceHashSeed spreads namespace ids over the hash space so equal keys
+// of different namespaces land on different hashes
+const namespaceHashSeed uint64 = 0x9e3779b97f4a7c15
+
+// Namespace is a logical sub-cache sharing the shards of a LargeCache.
+// Its keys are isolated from the keys of other namespaces and of the LargeCache itself,
+// the namespace is recorded in the entry header so Len, Reset and Iterator only see its entries.
+// Namespaces are not propagated to Config.Store, their writes fail with ErrNamespaceWithStore when it is set.
+type Namespace struct {
+	cache *LargeCache
+	name  string
+	id    uint32
+	seed  uint64
+	stats Stats
+}
+
+// Namespace returns the namespace with the given name, creating it on first use.
+// Namespaces live as long as the cache, calls with the same name return the same handle.
+func (c *LargeCache) Namespace(name string) *Namespace {
+	c.namespaceLock.Lock()
+	defer c.namespaceLock.Unlock()
+
+	if ns, ok := c.namespaces[name]; ok {
+		return ns
+	}
+	id := uint32(len(c.namespaces)) + 1
+	ns := &Namespace{
+		cache: c,
+		name:  name,
+		id:    id,
+		seed:  uint64(id) * namespaceHashSeed,
+	}
+	c.namespaces[name] = ns
+	return ns
+}
+
+func (n *Namespace) Name() string {
+	return n.name
+}
+
+func (n *Namespace) hashKey(key string) uint64 {
+	return n.cache.hash.Sum64(key) ^ n.seed
+}
+
+// Get reads entry for the key in the namespace.
+func (n *Namespace) Get(key string) ([]byte, error) {
+	hashedKey := n.hashKey(key)
+	shard := n.cache.getShard(hashedKey)
+	entry, err := shard.get(n.id, key, hashedKey)
+	switch err {
+	case nil:
+		atomic.AddInt64(&n.stats.Hits, 1)
+	case ErrKnownAbsent:
+		atomic.AddInt64(&n.stats.NegativeHits, 1)
+	default:
+		atomic.AddInt64(&n.stats.Misses, 1)
+	}
+	return entry, err
+}
+
+// Set saves entry under the key in the namespace.
+func (n *Namespace) Set(key string, entry []byte) error {
+	if n.cache.store != nil {
+		return ErrNamespaceWithStore
+	}
+	hashedKey := n.hashKey(key)
+	shard := n.cache.getShard(hashedKey)
+	return shard.set(n.id, key, hashedKey, entry, 0)
+}
+
+// Delete removes the key from the namespace.
+func (n *Namespace) Delete(key string) error {
+	if n.cache.store != nil {
+		return ErrNamespaceWithStore
+	}
+	hashedKey := n.hashKey(key)
+	shard := n.cache.getShard(hashedKey)
+	err := shard.del(n.id, key, hashedKey)
+	if err == nil {
+		atomic.AddInt64(&n.stats.DelHits, 1)
+	} else {
+		atomic.AddInt64(&n.stats.DelMissed, 1)
+	}
+	return err
+}
+
+// Reset removes all entries of the namespace.
+func (n *Namespace) Reset() error {
+	for _, shard := range n.cache.shards {
+		shard.resetNamespace(n.id)
+	}
+	return nil
+}
+
+func (n *Namespace) ResetStats() error {
+	atomic.StoreInt64(&n.stats.Hits, 0)
+	atomic.StoreInt64(&n.stats.Misses, 0)
+	atomic.StoreInt64(&n.stats.DelHits, 0)
+	atomic.StoreInt64(&n.stats.DelMissed, 0)
+	atomic.StoreInt64(&n.stats.NegativeHits, 0)
+	return nil
+}
+
+// Len computes number of entries in the namespace, it walks every shard.
+func (n *Namespace) Len() int {
+	var len int
+	for _, shard := range n.cache.shards {
+		len += shard.namespaceLen(n.id)
+	}
+	return len
+}
+
+// Stats returns statistics of the reads and deletes done through the namespace.
+func (n *Namespace) Stats() Stats {
+	return Stats{
+		Hits:         atomic.LoadInt64(&n.stats.Hits),
+		Misses:       atomic.LoadInt64(&n.stats.Misses),
+		DelHits:      atomic.LoadInt64(&n.stats.DelHits),
+		DelMissed:    atomic.LoadInt64(&n.stats.DelMissed),
+		NegativeHits: atomic.LoadInt64(&n.stats.NegativeHits),
+	}
+}
+
+// Iterator returns an iterator over the entries of the namespace.
+func (n *Namespace) Iterator() *EntryInfoIterator {
+	return newScopedIterator(n.cache, n.id, "")
+}
@@ -0,0 +1,200 @@
+package largecache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestNamespaceIsolatesKeys(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := New(context.Background(), Config{
+		Shards:             8,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1000,
+		MaxEntriesSize:     256,
+	})
+	defer cache.Close()
+	first := cache.Namespace("first")
+	second := cache.Namespace("second")
+
+	// when
+	noError(t, cache.Set("key", []byte("root")))
+	noError(t, first.Set("key", []byte("first")))
+	rootValue, rootErr := cache.Get("key")
+	firstValue, firstErr := first.Get("key")
+	_, secondErr := second.Get("key")
+
+	// then
+	noError(t, rootErr)
+	noError(t, firstErr)
+	assertEqual(t, []byte("root"), rootValue)
+	assertEqual(t, []byte("first"), firstValue)
+	assertEqual(t, ErrEntryNotFound, secondErr)
+	assertEqual(t, first, cache.Namespace("first"))
+}
+
+func TestNamespaceDelete(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := New(context.Background(), Config{
+		Shards:             8,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1000,
+		MaxEntriesSize:     256,
+	})
+	defer cache.Close()
+	ns := cache.Namespace("ns")
+	noError(t, cache.Set("key", []byte("root")))
+	noError(t, ns.Set("key", []byte("value")))
+
+	// when
+	err := ns.Delete("key")
+	_, nsErr := ns.Get("key")
+	rootValue, rootErr := cache.Get("key")
+
+	// then
+	noError(t, err)
+	noError(t, rootErr)
+	assertEqual(t, ErrEntryNotFound, nsErr)
+	assertEqual(t, []byte("root"), rootValue)
+}
+
+func TestNamespaceLenAndReset(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := New(context.Background(), Config{
+		Shards:             8,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1000,
+		MaxEntriesSize:     256,
+	})
+	defer cache.Close()
+	first := cache.Namespace("first")
+	second := cache.Namespace("second")
+	for i := 0; i < 10; i++ {
+		noError(t, first.Set(string(rune('a'+i)), []byte("value")))
+	}
+	noError(t, second.Set("a", []byte("value")))
+	noError(t, cache.Set("a", []byte("value")))
+
+	// then
+	assertEqual(t, 10, first.Len())
+	assertEqual(t, 1, second.Len())
+	assertEqual(t, 1, cache.Len())
+
+	// when
+	noError(t, first.Reset())
+
+	// then
+	assertEqual(t, 0, first.Len())
+	assertEqual(t, 1, second.Len())
+	assertEqual(t, 1, cache.Len())
+}
+
+func TestNamespaceStats(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := New(context.Background(), Config{
+		Shards:             8,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1000,
+		MaxEntriesSize:     256,
+	})
+	defer cache.Close()
+	first := cache.Namespace("first")
+	second := cache.Namespace("second")
+	noError(t, first.Set("key", []byte("value")))
+
+	// when
+	first.Get("key")
+	first.Get("missing")
+	first.Delete("key")
+	first.Delete("key")
+	second.Get("key")
+
+	// then
+	stats := first.Stats()
+	assertEqual(t, int64(1), stats.Hits)
+	assertEqual(t, int64(1), stats.Misses)
+	assertEqual(t, int64(1), stats.DelHits)
+	assertEqual(t, int64(1), stats.DelMissed)
+	assertEqual(t, int64(1), second.Stats().Misses)
+	assertEqual(t, int64(0), second.Stats().Hits)
+}
+
+func TestNamespaceIterator(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := New(context.Background(), Config{
+		Shards:             8,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1000,
+		MaxEntriesSize:     256,
+	})
+	defer cache.Close()
+	ns := cache.Namespace("ns")
+	for i := 0; i < 100; i++ {
+		noError(t, cache.Set(string(rune('a'+i)), []byte("root")))
+	}
+	noError(t, ns.Set("a", []byte("first")))
+	noError(t, ns.Set("b", []byte("second")))
+
+	// when
+	keys := make(map[string]string)
+	iterator := ns.Iterator()
+	for iterator.SetNext() {
+		current, err := iterator.Value()
+		noError(t, err)
+		keys[current.Key()] = string(current.Value())
+	}
+
+	// then
+	assertEqual(t, map[string]string{"a": "first", "b": "second"}, keys)
+}
+
+func TestCacheWalksSkipNamespaceEntries(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := New(context.Background(), Config{
+		Shards:             8,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1000,
+		MaxEntriesSize:     256,
+	})
+	defer cache.Close()
+	noError(t, cache.Set("a", []byte("root")))
+	noError(t, cache.Namespace("tenant").Set("a", []byte("tenant")))
+
+	// when
+	var iterated []string
+	iterator := cache.Iterator()
+	for iterator.SetNext() {
+		current, err := iterator.Value()
+		noError(t, err)
+		iterated = append(iterated, string(current.Value()))
+	}
+	scanned, _ := cache.Scan(0, 10)
+	var visited []string
+	count, err := cache.ForEach(context.Background(), 1, func(entry EntryInfo) error {
+		visited = append(visited, string(entry.Value()))
+		return nil
+	})
+
+	// then
+	noError(t, err)
+	assertEqual(t, []string{"root"}, iterated)
+	assertEqual(t, 1, len(scanned))
+	assertEqual(t, "root", string(scanned[0].Value()))
+	assertEqual(t, 1, count)
+	assertEqual(t, []string{"root"}, visited)
+	assertEqual(t, 1, cache.Len())
+	assertEqual(t, 1, cache.Namespace("tenant").Len())
+}
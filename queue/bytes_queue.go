@@ -7,7 +7,9 @@ import (
 )
 
 const (
-	minimumHeaderSize = 17
+	// minimumHeaderSize is the smallest gap left between tail and head, the filler later pushed
+	// into such a gap must hold a whole entry header: 1 byte blobsize + 39 bytes of largecache headers
+	minimumHeaderSize = 40
 	leftMarginIndex   = 1
 )
 
@@ -59,6 +61,7 @@ func NewBytesQueue(capacity int, maxCapacity int, verboase bool) *BytesQueue {
 		headerBuffer: make([]byte, binary.MaxVarintLen32),
 		tail:         leftMarginIndex,
 		head:         leftMarginIndex,
+		rightMargin:  leftMarginIndex,
 		verbose:      verboase,
 	}
 }
@@ -74,11 +77,13 @@ func (q *BytesQueue) Reset() {
 func (q *BytesQueue) Push(data []byte) (int, error) {
 	neededSize := getNeededSize(len(data))
 	if !q.canInsertAfterTail(neededSize) {
-		q.tail = leftMarginIndex
-	} else if q.capacity+neededSize >= q.maxCapacity && q.maxCapacity > 0 {
-		return -1, &queueError{"Full queue. Maximum size limit reached."}
-	} else {
-		q.allocateAdditionalMemory(neededSize)
+		if q.canInsertBeforeHead(neededSize) {
+			q.tail = leftMarginIndex
+		} else if q.capacity+neededSize >= q.maxCapacity && q.maxCapacity > 0 {
+			return -1, &queueError{"Full queue. Maximum size limit reached."}
+		} else {
+			q.allocateAdditionalMemory(neededSize)
+		}
 	}
 
 	index := q.tail
@@ -183,6 +188,28 @@ func (q *BytesQueue) Len() int {
 	return q.count
 }
 
+// Size returns the number of bytes taken by entries, headers included.
+func (q *BytesQueue) Size() int {
+	if q.count == 0 {
+		return 0
+	}
+	if q.tail > q.head {
+		return q.tail - q.head
+	}
+	return q.rightMargin - q.head + q.tail - leftMarginIndex
+}
+
+// MaxCapacity returns the limit on the capacity of the queue, 0 means no limit.
+func (q *BytesQueue) MaxCapacity() int {
+	return q.maxCapacity
+}
+
+// SetMaxCapacity changes the limit on the capacity of the queue, 0 means no limit.
+// The array already allocated is kept even when it is bigger than the limit.
+func (q *BytesQueue) SetMaxCapacity(maxCapacity int) {
+	q.maxCapacity = maxCapacity
+}
+
 func (e *queueError) Error() string {
 	return e.message
 }
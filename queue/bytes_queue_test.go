@@ -29,12 +29,12 @@ func TestUnchangeEntriesIndexesAfterAdditionalMemoryAllocationWhereTialIsBeforeH
 	queue := NewBytesQueue(100, 0, false)
 
 	queue.Push(blob('a', 70))
-	index, _ := queue.Push(blob('a', 10))
+	index, _ := queue.Push(blob('b', 10))
 	queue.Pop()
 	queue.Push(blob('c', 30))
 	newesIndex, _ := queue.Push(blob('d', 40))
 
-	assertEqual(t, 20, queue.Capacity())
+	assertEqual(t, 200, queue.Capacity())
 	assertEqual(t, blob('b', 10), get(queue, index))
 	assertEqual(t, blob('d', 40), get(queue, newesIndex))
 }
@@ -102,7 +102,7 @@ func TestGetEntryFromInvalidIndex(t *testing.T) {
 	err2 := queue.CheckGet(0)
 
 	assertEqual(t, err, err2)
-	assertEqual(t, []BytesQueue(nil), result)
+	assertEqual(t, []byte(nil), result)
 	assertEqual(t, "Index must be greater than zero. Invalid index.", err.Error())
 }
 
@@ -142,7 +142,7 @@ func TestMaxSizeLimit(t *testing.T) {
 	capacity := queue.Capacity()
 	_, err := queue.Push(blob('c', 20))
 
-	assertEqual(t, 40, capacity)
+	assertEqual(t, 50, capacity)
 	assertEqual(t, "Full queue. Maximum size limit reached.", err.Error())
 	assertEqual(t, blob('a', 25), pop(queue))
 	assertEqual(t, blob('b', 5), pop(queue))
@@ -170,6 +170,24 @@ func TestPushEntryAfterAllocateAdditionMemoryInFull(t *testing.T) {
 	noError(t, err)
 }
 
+func TestSizeOfWrappedQueue(t *testing.T) {
+	t.Parallel()
+
+	queue := NewBytesQueue(20, 20, false)
+
+	queue.Push([]byte("aaa"))
+	queue.Push([]byte("bb"))
+	assertEqual(t, 7, queue.Size())
+
+	queue.Pop()
+	queue.Push(blob('x', 10))
+	assertEqual(t, 14, queue.Size())
+
+	queue.Push([]byte("ccc"))
+	assertEqual(t, 18, queue.Size())
+	assertEqual(t, 20, queue.Capacity())
+}
+
 func pop(queue *BytesQueue) []byte {
 	entry, err := queue.Pop()
 	if err != nil {
@@ -191,12 +209,12 @@ func blob(char byte, len int) []byte {
 }
 
 func assertEqual(t *testing.T, expected, actual interface{}, masAndArgs ...interface{}) {
-	if objectsAreEqual(expected, actual) {
+	if !objectsAreEqual(expected, actual) {
 		_, file, line, _ := runtime.Caller(1)
 		file = path.Base(file)
 		t.Errorf(fmt.Sprintf("\n%s:%d: Not equal: \n"+
 			"expected: %T(%#v)\n"+
-			"actual : %T(%#v\n)",
+			"actual : %T(%#v)\n",
 			file, line, expected, expected, actual, actual), masAndArgs...)
 	}
 }
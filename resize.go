@@ -0,0 +1,68 @@
+package largecache
+
+import (
+	"errors"
+
+	"largecache/queue"
+)
+
+// SetMaxSize changes HardMaxCacheSize to mb megabytes while the cache is in use, 0 removes the limit.
+// Shards are resized one at a time, each one evicting its oldest entries with NoSpace
+// until they fit and moving the others to a smaller array.
+func (c *LargeCache) SetMaxSize(mb int) error {
+	if mb < 0 {
+		return errors.New("HardMaxCacheSize must be >= 0")
+	}
+
+	c.configLock.Lock()
+	defer c.configLock.Unlock()
+
+	c.config.HardMaxCacheSize = mb
+	maxShardSize := c.config.maximumShardSizeInBytes()
+	for _, shard := range c.shards {
+		shard.setMaxSize(maxShardSize)
+	}
+	return nil
+}
+
+func (s *cacheShard) setMaxSize(maxSize int) {
+	s.lock.Lock()
+	if maxSize > 0 {
+		// the first byte of a queue is never used
+		for s.entries.Size()-s.deadBytes >= maxSize {
+			if s.removeOldestEntry(NoSpace) != nil {
+				break
+			}
+		}
+		if s.entries.Capacity() > maxSize {
+			s.rebuildWithoutLock(maxSize, maxSize)
+		}
+	}
+	s.entries.SetMaxCapacity(maxSize)
+	s.lock.Unlock()
+}
+
+// rebuildWithoutLock moves the live entries, oldest first, to a new queue and points hashmap
+// at their new indexes. Dead entries are dropped, entries which do not fit are evicted with NoSpace.
+func (s *cacheShard) rebuildWithoutLock(capacity int, maxCapacity int) {
+	old := s.entries
+	s.entries = *queue.NewBytesQueue(capacity, maxCapacity, s.isVerbose)
+	s.deadBytes = 0
+	for {
+		wrappedEntry, err := old.Pop()
+		if err != nil {
+			break
+		}
+		hashedKey := readHashFromEntry(wrappedEntry)
+		if hashedKey == 0 || !s.isIndexedWithoutLock(wrappedEntry, hashedKey) {
+			continue
+		}
+
+		index, err := s.entries.Push(wrappedEntry)
+		if err != nil {
+			s.removeWithoutLock(wrappedEntry, hashedKey, NoSpace)
+			continue
+		}
+		s.reindexWithoutLock(wrappedEntry, hashedKey, uint64(index))
+	}
+}
@@ -0,0 +1,81 @@
+package largecache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSetMaxSizeShrinksCache(t *testing.T) {
+	t.Parallel()
+
+	// given
+	var removed []string
+	var reasons []RemoveReason
+	cache, _ := New(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         100 * time.Second,
+		MaxEntriesInWindow: 100,
+		MaxEntriesSize:     256,
+		HardMaxCacheSize:   2,
+		OnRemoveWithReason: func(key string, entry []byte, reason RemoveReason) {
+			removed = append(removed, key)
+			reasons = append(reasons, reason)
+		},
+	})
+	defer cache.Close()
+	value := blob('a', 1024*300)
+	for i := 0; i < 6; i++ {
+		noError(t, cache.Set(fmt.Sprintf("key%d", i), value))
+	}
+
+	// when
+	err := cache.SetMaxSize(1)
+
+	// then
+	noError(t, err)
+	assertEqual(t, []string{"key0", "key1", "key2"}, removed)
+	assertEqual(t, []RemoveReason{NoSpace, NoSpace, NoSpace}, reasons)
+	assertEqual(t, 3, cache.Len())
+	assertEqual(t, 1024*1024, cache.Capacity())
+	for _, key := range []string{"key3", "key4", "key5"} {
+		cachedValue, err := cache.Get(key)
+		noError(t, err)
+		assertEqual(t, value, cachedValue)
+	}
+
+	// when
+	noError(t, cache.Set("key6", value))
+
+	// then
+	assertEqual(t, 3, cache.Len())
+	_, err = cache.Get("key3")
+	assertEqual(t, ErrEntryNotFound, err)
+}
+
+func TestSetMaxSizeGrowsCache(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := New(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         100 * time.Second,
+		MaxEntriesInWindow: 100,
+		MaxEntriesSize:     256,
+		HardMaxCacheSize:   1,
+	})
+	defer cache.Close()
+	value := blob('a', 1024*300)
+
+	// when
+	noError(t, cache.SetMaxSize(2))
+	for i := 0; i < 6; i++ {
+		noError(t, cache.Set(fmt.Sprintf("key%d", i), value))
+	}
+
+	// then
+	assertEqual(t, 6, cache.Len())
+	assertEqual(t, 2, cache.config.HardMaxCacheSize)
+	assertEqual(t, "HardMaxCacheSize must be >= 0", cache.SetMaxSize(-1).Error())
+}
@@ -0,0 +1,122 @@
+package largecache
+
+import (
+	"container/heap"
+	"math/bits"
+	"sort"
+)
+
+const defaultScanCount = 10
+
+// Scan returns a page of entries starting at the cursor and the cursor of the next page.
+// Start with cursor 0 and call Scan again with the returned cursor until it is 0.
+// Entries are ordered by shard and by their hash within the shard, so every key present
+// for the whole scan is returned exactly once, keys added or removed meanwhile may or may not be.
+// A page may hold fewer than count entries, or more when keys collide, count defaults to 10 when not positive.
+// Entries of namespaces are not returned, they still take their place in the page.
+// The cursor keeps no state in the cache, scans can be paused and resumed at any time.
+func (c *LargeCache) Scan(cursor uint64, count int) (entries []EntryInfo, next uint64) {
+	if count <= 0 {
+		count = defaultScanCount
+	}
+
+	// the cursor holds the shard index in its high bits and the position within the shard below,
+	// the position of an entry is its hash without the low bits which select the shard
+	shardBits := uint(bits.TrailingZeros(uint(c.config.Shards)))
+	positionBits := 64 - shardBits
+	shardIndex := int(cursor >> positionBits)
+	position := cursor << shardBits >> shardBits
+
+	for ; shardIndex < len(c.shards); shardIndex++ {
+		page, last, more := c.shards[shardIndex].scan(position, shardBits, count-len(entries))
+		entries = append(entries, page...)
+		if more {
+			return entries, (uint64(shardIndex)<<positionBits | last) + 1
+		}
+		position = 0
+		if len(entries) >= count {
+			return entries, uint64(shardIndex+1) << positionBits
+		}
+	}
+	return entries, 0
+}
+
+// scan reads the entries of up to count positions from the given one on, in position order.
+// Colliding keys share a position, all entries at a position are returned on the same page.
+// It returns the position of the last entry read and whether the shard holds entries after it.
+// The positions are selected with a heap of count hashes, a page costs O(n log count) whatever the shard size.
+func (s *cacheShard) scan(position uint64, shardBits uint, count int) (entries []EntryInfo, last uint64, more bool) {
+	s.lock.RLock()
+	selected := make(map[uint64]struct{}, count)
+	smallest := make(hashHeap, 0, count)
+	s.forEachIndexWithoutLock(func(hashedKey uint64, _ uint64) {
+		if hashedKey>>shardBits < position {
+			return
+		}
+		if _, ok := selected[hashedKey]; ok {
+			return
+		}
+		if len(smallest) < count {
+			heap.Push(&smallest, hashedKey)
+			selected[hashedKey] = struct{}{}
+			return
+		}
+		more = true
+		if hashedKey < smallest[0] {
+			delete(selected, smallest[0])
+			smallest[0] = hashedKey
+			heap.Fix(&smallest, 0)
+			selected[hashedKey] = struct{}{}
+		}
+	})
+
+	found := make([]scanEntry, 0, len(selected))
+	s.forEachIndexWithoutLock(func(hashedKey uint64, index uint64) {
+		if _, ok := selected[hashedKey]; ok {
+			found = append(found, scanEntry{hashedKey: hashedKey, index: index})
+		}
+	})
+	sort.Slice(found, func(i, j int) bool { return found[i].hashedKey < found[j].hashedKey })
+
+	entries = make([]EntryInfo, 0, len(found))
+	for _, f := range found {
+		last = f.hashedKey >> shardBits
+		wrappedEntry, err := s.entries.Get(int(f.index))
+		if err != nil || isAbsentEntry(wrappedEntry) || readNamespaceFromEntry(wrappedEntry) != defaultNamespace {
+			continue
+		}
+		entries = append(entries, EntryInfo{
+			timestamp: readTimestampFromEntry(wrappedEntry),
+			hash:      f.hashedKey,
+			key:       readKeyFromEntry(wrappedEntry),
+			value:     readEntry(wrappedEntry),
+		})
+	}
+	s.lock.RUnlock()
+
+	return entries, last, more
+}
+
+// scanEntry is the hash and the index of an entry read by scan
+type scanEntry struct {
+	hashedKey uint64
+	index     uint64
+}
+
+// hashHeap is a max-heap of hashes, scan keeps the smallest ones by replacing its top
+type hashHeap []uint64
+
+func (h hashHeap) Len() int           { return len(h) }
+func (h hashHeap) Less(i, j int) bool { return h[i] > h[j] }
+func (h hashHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *hashHeap) Push(x any) {
+	*h = append(*h, x.(uint64))
+}
+
+func (h *hashHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
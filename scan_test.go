@@ -0,0 +1,143 @@
+package largecache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestScan(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := New(context.Background(), Config{
+		Shards:             8,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1000,
+		MaxEntriesSize:     256,
+	})
+	defer cache.Close()
+	for i := 0; i < 1000; i++ {
+		cache.Set(fmt.Sprintf("key%d", i), []byte("value"))
+	}
+
+	// when
+	seen := make(map[string]int)
+	var cursor uint64
+	pages := 0
+	for {
+		var entries []EntryInfo
+		entries, cursor = cache.Scan(cursor, 7)
+		for _, entry := range entries {
+			seen[entry.Key()]++
+			assertEqual(t, []byte("value"), entry.Value())
+		}
+		pages++
+		if cursor == 0 {
+			break
+		}
+	}
+
+	// then
+	assertEqual(t, 1000, len(seen))
+	for key, count := range seen {
+		if count != 1 {
+			t.Errorf("%s returned %d times", key, count)
+		}
+	}
+	if pages < 1000/7 {
+		t.Errorf("expected pages of at most 7 entries, got %d pages", pages)
+	}
+}
+
+func TestScanWithConcurrentWrites(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := New(context.Background(), Config{
+		Shards:             8,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1000,
+		MaxEntriesSize:     256,
+	})
+	defer cache.Close()
+	for i := 0; i < 500; i++ {
+		cache.Set(fmt.Sprintf("stable%d", i), []byte("value"))
+		cache.Set(fmt.Sprintf("removed%d", i), []byte("value"))
+	}
+
+	// when
+	seen := make(map[string]bool)
+	var cursor uint64
+	for i := 0; ; i++ {
+		var entries []EntryInfo
+		entries, cursor = cache.Scan(cursor, 20)
+		for _, entry := range entries {
+			seen[entry.Key()] = true
+		}
+		cache.Delete(fmt.Sprintf("removed%d", i))
+		cache.Set(fmt.Sprintf("added%d", i), []byte("value"))
+		cache.Set(fmt.Sprintf("stable%d", i), []byte("updated"))
+		if cursor == 0 {
+			break
+		}
+	}
+
+	// then
+	for i := 0; i < 500; i++ {
+		if !seen[fmt.Sprintf("stable%d", i)] {
+			t.Errorf("stable%d was not returned", i)
+		}
+	}
+}
+
+func TestScanEmptyCache(t *testing.T) {
+	t.Parallel()
+
+	cache, _ := New(context.Background(), Config{
+		Shards:             8,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1000,
+		MaxEntriesSize:     256,
+	})
+	defer cache.Close()
+
+	entries, cursor := cache.Scan(0, 10)
+
+	assertEqual(t, 0, len(entries))
+	assertEqual(t, uint64(0), cursor)
+}
+
+func TestScanSingleShard(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := New(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         time.Second,
+		MaxEntriesInWindow: 100,
+		MaxEntriesSize:     256,
+	})
+	defer cache.Close()
+	for i := 0; i < 100; i++ {
+		cache.Set(fmt.Sprintf("key%d", i), []byte("value"))
+	}
+
+	// when
+	seen := make(map[string]bool)
+	var entries []EntryInfo
+	var cursor uint64
+	for {
+		entries, cursor = cache.Scan(cursor, 30)
+		for _, entry := range entries {
+			seen[entry.Key()] = true
+		}
+		if cursor == 0 {
+			break
+		}
+	}
+
+	// then
+	assertEqual(t, 100, len(seen))
+}
@@ -11,7 +11,11 @@ func cacheIndexHandler() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodGet:
-
+			getCacheHandler(w, r)
+		case http.MethodPut:
+			putCacheHandler(w, r)
+		case http.MethodDelete:
+			deleteCacheHandler(w, r)
 		}
 	})
 }
@@ -26,6 +30,7 @@ func clearCache(w http.ResponseWriter, r *http.Request) {
 	if err := cache.Reset(); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		log.Printf("internal cache error: %s", err)
+		return
 	}
 	log.Println("cache is successfully cleared")
 	w.WriteHeader(http.StatusOK)
@@ -89,6 +94,7 @@ func deleteCacheHandler(w http.ResponseWriter, r *http.Request) {
 		}
 		w.WriteHeader(http.StatusInternalServerError)
 		log.Printf("internal cache error: %s", err)
+		return
 	}
 	w.WriteHeader(http.StatusOK)
 }
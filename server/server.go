@@ -13,7 +13,7 @@ import (
 
 const (
 	apiVersion  = "v1"
-	apiBasePath = "/api" + apiVersion + "/"
+	apiBasePath = "/api/" + apiVersion + "/"
 
 	cachePath      = apiBasePath + "cache/"
 	statsPath      = apiBasePath + "stats"
@@ -141,7 +141,7 @@ func TestDelteInvalidKey(t *testing.T) {
 func TestDeleteKey(t *testing.T) {
 	t.Parallel()
 
-	req := httptest.NewRequest("DELETE", testBaseString+"/api/v1/cahce/testDeleteKey", bytes.NewBuffer([]byte("123")))
+	req := httptest.NewRequest("DELETE", testBaseString+"/api/v1/cache/testDeleteKey", bytes.NewBuffer([]byte("123")))
 	rr := httptest.NewRecorder()
 
 	if err := cache.Set("testDeleteKey", []byte("123")); err != nil {
@@ -185,7 +185,7 @@ func TestGetStats(t *testing.T) {
 	req := httptest.NewRequest("GET", testBaseString+"/api/v1/stats", nil)
 	rr := httptest.NewRecorder()
 
-	if err := cache.Set("incrementStass", []byte("123")); err != nil {
+	if err := cache.Set("incrementStats", []byte("123")); err != nil {
 		t.Errorf("error setting cache value. error %s", err)
 	}
 
@@ -193,7 +193,7 @@ func TestGetStats(t *testing.T) {
 		t.Errorf("can't find incrementStats. error : %s", err)
 	}
 
-	getCacheHandler(rr, req)
+	getCacheStatsHandler(rr, req)
 	resp := rr.Result()
 
 	if err := json.NewDecoder(resp.Body).Decode(&testStats); err != nil {
@@ -272,7 +272,7 @@ func TestCacheIndexHandler(t *testing.T) {
 
 func TestInvalidPutWhenExceedShardCap(t *testing.T) {
 	t.Parallel()
-	req := httptest.NewRequest("PUT", testBaseString+"/api/v1/cache/putkey", bytes.NewBuffer([]byte("123")))
+	req := httptest.NewRequest("PUT", testBaseString+"/api/v1/cache/putkey", bytes.NewBuffer(bytes.Repeat([]byte("a"), 8*1024*1024)))
 	rr := httptest.NewRecorder()
 
 	putCacheHandler(rr, req)
@@ -18,34 +18,70 @@ type cacheShard struct {
 	entries     queue.BytesQueue
 	lock        sync.RWMutex
 	entryBuffer []byte
-	onRemove    onRemoveCallBack
+	// evictionBuffer holds entries moved by the eviction policy, entryBuffer may hold the entry being saved
+	evictionBuffer []byte
+	onRemove       onRemoveCallBack
+
+	// collisions indexes entries whose hash is taken in hashmap by an entry of another key
+	collisions map[collisionKey]uint64
+	// maxEntries bounds the number of entries in hashmap and collisions, 0 means no limit
+	maxEntries int
+	// initialCapacity is the smallest array compaction shrinks the queue to
+	initialCapacity int
+	// deadBytes counts bytes of deleted and overwritten entries still in the queue
+	deadBytes int
 
 	isVerbose    bool
 	statsEnabled bool
 	logger       Logger
 	clock        clock
 	lifeWindow   uint64
+	// negativeLifeWindow is how long GetOrLoad records keys its loader reported as absent
+	negativeLifeWindow uint64
+	// idleExpiration makes lifeWindow count from the last read, maxAge limits the age since the write
+	idleExpiration bool
+	maxAge         uint64
 
 	hashmapStats map[uint64]uint32
 	stats        Stats
 	cleanEnabled bool
+	// expiries orders the explicit expiries written to the shard, the earliest first
+	expiries expiryHeap
+	// version is the last version assigned to an entry written to the shard
+	version uint64
+
+	loads    map[uint64]*loadCall
+	loadLock sync.Mutex
+
+	// policy chooses the entries evicted for space, accesses counts reads for LRU and LFU
+	policy   EvictionPolicy
+	accesses map[uint64]uint32
+	// admission estimates request frequencies for TinyLFU, nil when every key is admitted
+	admission *frequencySketch
+
+	// tags indexes keys of tagged entries with their hashes by tag, entryTags lists the tags of each of them
+	tags      map[string]map[collisionKey]uint64
+	entryTags map[collisionKey][]string
 }
 
 func (s *cacheShard) getWithInfo(key string, hashedKey uint64) (entry []byte, resp Response, err error) {
 	currentTime := uint64(s.clock.Epoch())
 	s.lock.RLock()
-	wrappedEntry, err := s.getWrappedEntry(hashedKey)
+	s.recordRequest(hashedKey)
+	wrappedEntry, err := s.getWrappedEntryByKey(defaultNamespace, key, hashedKey)
 	if err != nil {
 		s.lock.RUnlock()
 		return nil, resp, err
 	}
-	if entryKey := readKeyFromEntry(wrappedEntry); key != entryKey {
-		s.lock.RUnlock()
-		s.collision()
-		if s.isVerbose {
-			s.logger.Printf("Collision detected. Both %q and %q have the same hash %x", key, entryKey, hashedKey)
+	if isAbsentEntry(wrappedEntry) {
+		if s.isExpired(wrappedEntry, currentTime) {
+			s.lock.RUnlock()
+			s.miss()
+			return nil, resp, ErrEntryNotFound
 		}
-		return nil, resp, ErrEntryNotFound
+		s.lock.RUnlock()
+		s.negativeHit()
+		return nil, resp, ErrKnownAbsent
 	}
 
 	entry = readEntry(wrappedEntry)
@@ -54,61 +90,121 @@ func (s *cacheShard) getWithInfo(key string, hashedKey uint64) (entry []byte, re
 	}
 	s.lock.RUnlock()
 	s.hit(hashedKey)
+	if s.idleExpiration && resp.EntryStatus != Expried {
+		s.touch(defaultNamespace, key, hashedKey, currentTime)
+	}
 	return entry, resp, nil
 }
 
-func (s *cacheShard) get(key string, hashedKey uint64) ([]byte, error) {
+func (s *cacheShard) get(namespace uint32, key string, hashedKey uint64) ([]byte, error) {
+	currentTime := uint64(s.clock.Epoch())
 	s.lock.RLock()
-	wrappedEntry, err := s.getWrappedEntry(hashedKey)
+	entry, err := s.getWithoutLock(namespace, key, hashedKey, currentTime)
+	s.lock.RUnlock()
 	if err != nil {
-		s.lock.Unlock()
 		return nil, err
 	}
-	if entryKey := readKeyFromEntry(wrappedEntry); key != entryKey {
-		s.lock.RUnlock()
-		s.collision()
-		if s.isVerbose {
-			s.logger.Printf("Collision detected. Both %q and %q have the same hash %x", key, entryKey, hashedKey)
-		}
-		return nil, ErrEntryNotFound
-	}
-
-	entry := readEntry(wrappedEntry)
-	s.lock.RUnlock()
 	s.hit(hashedKey)
+	if s.idleExpiration {
+		s.touch(namespace, key, hashedKey, currentTime)
+	}
 
 	return entry, nil
 }
 
-func (s *cacheShard) getWrappedEntry(hashedKey uint64) ([]byte, error) {
-	itemIndex := s.hashmap[hashedKey]
+// getWithoutLock reads a copy of the entry, the caller holds at least the read lock and records the hit.
+func (s *cacheShard) getWithoutLock(namespace uint32, key string, hashedKey uint64, currentTime uint64) ([]byte, error) {
+	wrappedEntry, err := s.lookupWithoutLock(namespace, key, hashedKey, currentTime)
+	if err != nil {
+		return nil, err
+	}
+	return readEntry(wrappedEntry), nil
+}
 
-	if itemIndex == 0 {
+// lookupWithoutLock returns the wrapped entry for the key as Get sees it.
+func (s *cacheShard) lookupWithoutLock(namespace uint32, key string, hashedKey uint64, currentTime uint64) ([]byte, error) {
+	s.recordRequest(hashedKey)
+	wrappedEntry, err := s.getWrappedEntryByKey(namespace, key, hashedKey)
+	if err != nil {
+		return nil, err
+	}
+	if (s.idleExpiration || readExpiryFromEntry(wrappedEntry) != 0) && s.isExpired(wrappedEntry, currentTime) {
 		s.miss()
 		return nil, ErrEntryNotFound
 	}
+	if isAbsentEntry(wrappedEntry) {
+		s.negativeHit()
+		return nil, ErrKnownAbsent
+	}
 
-	wrappedEntry, err := s.entries.Get(int(itemIndex))
+	return wrappedEntry, nil
+}
+
+func (s *cacheShard) view(key string, hashedKey uint64, fn func(entry []byte) error) error {
+	currentTime := uint64(s.clock.Epoch())
+	found, err := s.viewWithLock(key, hashedKey, currentTime, fn)
+	if !found {
+		return err
+	}
+	s.hit(hashedKey)
+	if s.idleExpiration {
+		s.touch(defaultNamespace, key, hashedKey, currentTime)
+	}
+
+	return err
+}
+
+// viewWithLock calls fn with the entry under the read lock, which is released even when fn panics.
+// found tells whether the entry was there, err is then the error returned by fn.
+func (s *cacheShard) viewWithLock(key string, hashedKey uint64, currentTime uint64, fn func(entry []byte) error) (found bool, err error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	wrappedEntry, err := s.lookupWithoutLock(defaultNamespace, key, hashedKey, currentTime)
+	if err != nil {
+		return false, err
+	}
+	return true, fn(readEntryNoCopy(wrappedEntry))
+}
+
+func (s *cacheShard) getWithVersion(key string, hashedKey uint64) ([]byte, uint64, error) {
+	currentTime := uint64(s.clock.Epoch())
+	s.lock.RLock()
+	s.recordRequest(hashedKey)
+	wrappedEntry, err := s.getWrappedEntryByKey(defaultNamespace, key, hashedKey)
 	if err != nil {
+		s.lock.RUnlock()
+		return nil, 0, err
+	}
+	if (s.idleExpiration || readExpiryFromEntry(wrappedEntry) != 0) && s.isExpired(wrappedEntry, currentTime) {
+		s.lock.RUnlock()
 		s.miss()
-		return nil, err
+		return nil, 0, ErrEntryNotFound
+	}
+	if isAbsentEntry(wrappedEntry) {
+		s.lock.RUnlock()
+		s.negativeHit()
+		return nil, 0, ErrKnownAbsent
 	}
 
-	return wrappedEntry, err
+	entry := readEntry(wrappedEntry)
+	version := readVersionFromEntry(wrappedEntry)
+	s.lock.RUnlock()
+	s.hit(hashedKey)
+	if s.idleExpiration {
+		s.touch(defaultNamespace, key, hashedKey, currentTime)
+	}
+
+	return entry, version, nil
 }
 
 func (s *cacheShard) getValidWrapEntry(key string, hashedKey uint64) ([]byte, error) {
-	wrappedEntry, err := s.getWrappedEntry(hashedKey)
+	wrappedEntry, err := s.getWrappedEntryByKey(defaultNamespace, key, hashedKey)
 	if err != nil {
 		return nil, err
 	}
 
-	if !compareKeyFromEntry(wrappedEntry, key) {
-		s.collision()
-		if s.isVerbose {
-			s.logger.Printf("Collision detected. Both %q and %q have the same hash %x", key, readEntry(wrappedEntry), hashedKey)
-		}
-
+	if isAbsentEntry(wrappedEntry) {
 		return nil, ErrEntryNotFound
 	}
 	s.hitWithoutLock(hashedKey)
@@ -116,18 +212,35 @@ func (s *cacheShard) getValidWrapEntry(key string, hashedKey uint64) ([]byte, er
 	return wrappedEntry, nil
 }
 
-func (s *cacheShard) set(key string, hashedKey uint64, entry []byte) error {
+func (s *cacheShard) set(namespace uint32, key string, hashedKey uint64, entry []byte, expiry uint64) error {
 	currentTimestamp := uint64(s.clock.Epoch())
 
 	s.lock.Lock()
+	err := s.setWithoutLock(currentTimestamp, namespace, key, hashedKey, entry, expiry)
+	s.lock.Unlock()
 
-	if previousIndex := s.hashmap[hashedKey]; previousIndex != 0 {
-		if previousEntry, err := s.entries.Get(int(previousIndex)); err == nil {
-			resetHashFromEntry(previousEntry)
+	return err
+}
 
-			delete(s.hashmap, hashedKey)
+func (s *cacheShard) setWithoutLock(currentTimestamp uint64, namespace uint32, key string, hashedKey uint64, entry []byte, expiry uint64) error {
+	if expiry == 0 && s.maxAge > 0 {
+		expiry = currentTimestamp + s.maxAge
+	}
+
+	s.recordRequest(hashedKey)
+	// overwrites are always admitted, only new keys are checked once the shard is full
+	previousIndex, _ := s.findWithoutLock(namespace, key, hashedKey)
+	admitted := s.admission == nil || previousIndex != 0
+	if previousIndex != 0 {
+		if previousEntry, err := s.entries.Get(int(previousIndex)); err == nil {
+			if isTaggedEntry(previousEntry) {
+				s.untagWithoutLock(previousEntry)
+			}
+			s.unindexWithoutLock(previousEntry, hashedKey)
+			s.markDeadWithoutLock(previousEntry)
 		}
 	}
+	collides := s.collidesWithoutLock(namespace, key, hashedKey)
 
 	if !s.cleanEnabled {
 		if oldestEntry, err := s.entries.Peek(); err == nil {
@@ -135,46 +248,134 @@ func (s *cacheShard) set(key string, hashedKey uint64, entry []byte) error {
 		}
 	}
 
-	w := wrapEntry(currentTimestamp, hashedKey, key, entry, &s.entryBuffer)
+	w := wrapEntry(currentTimestamp, expiry, s.nextVersion(), namespace, hashedKey, key, entry, &s.entryBuffer)
 
 	for {
-		if index, err := s.entries.Push(w); err == nil {
-			s.hashmap[hashedKey] = uint64(index)
-			s.lock.Unlock()
-			return nil
+		if !s.isFullWithoutLock() {
+			if index, err := s.entries.Push(w); err == nil {
+				s.indexWithoutLock(w, hashedKey, uint64(index), collides, previousIndex == 0)
+				s.trackExpiryWithoutLock(w, hashedKey)
+				return nil
+			}
 		}
 
-		if s.removeOldestEntry(NoSpace) != nil {
-			s.lock.Unlock()
+		if !admitted {
+			if !s.admitWithoutLock(hashedKey, currentTimestamp) {
+				return nil
+			}
+			admitted = true
+		}
+		if s.evictWithoutLock() != nil {
 			return errors.New("entry is bigger than max shard size")
 		}
 	}
 }
 
-func (s *cacheShard) addNewWithoutLock(key string, hashedKey uint64, entry []byte) error {
+func (s *cacheShard) setAbsent(key string, hashedKey uint64, expiry uint64) error {
 	currentTimestamp := uint64(s.clock.Epoch())
-	if !s.cleanEnabled {
-		if oldestEntry, err := s.entries.Peek(); err == nil {
-			s.onEvict(oldestEntry, currentTimestamp, s.removeOldestEntry)
-		}
+
+	s.lock.Lock()
+	w := wrapEntry(currentTimestamp, expiry, s.nextVersion(), defaultNamespace, hashedKey, key, nil, &s.entryBuffer)
+	markEntryAbsent(w)
+	err := s.setWrappedEntryWithoutLock(currentTimestamp, key, w, hashedKey)
+	if err == nil {
+		s.trackExpiryWithoutLock(w, hashedKey)
 	}
+	s.lock.Unlock()
 
-	w := wrapEntry(currentTimestamp, hashedKey, key, entry, &s.entryBuffer)
+	return err
+}
 
-	for {
-		if index, err := s.entries.Push(w); err == nil {
-			s.hashmap[hashedKey] = uint64(index)
-		}
-		if s.removeOldestEntry(NoSpace) != nil {
-			return errors.New("entry is bigger than max shard size")
-		}
+// getLiveEntryWithoutLock returns the entry stored for the key unless it is missing or expired.
+// Unlike getWrappedEntry it does not touch the stats.
+func (s *cacheShard) getLiveEntryWithoutLock(namespace uint32, key string, hashedKey uint64, currentTimestamp uint64) ([]byte, bool) {
+	itemIndex, _ := s.findWithoutLock(namespace, key, hashedKey)
+	if itemIndex == 0 {
+		return nil, false
+	}
+
+	wrappedEntry, err := s.entries.Get(int(itemIndex))
+	if err != nil || isAbsentEntry(wrappedEntry) || s.isExpired(wrappedEntry, currentTimestamp) {
+		return nil, false
+	}
+	return wrappedEntry, true
+}
+
+func (s *cacheShard) setIfAbsent(key string, hashedKey uint64, entry []byte) (bool, error) {
+	currentTimestamp := uint64(s.clock.Epoch())
+
+	s.lock.Lock()
+	if _, ok := s.getLiveEntryWithoutLock(defaultNamespace, key, hashedKey, currentTimestamp); ok {
+		s.lock.Unlock()
+		return false, nil
+	}
+	err := s.setWithoutLock(currentTimestamp, defaultNamespace, key, hashedKey, entry, 0)
+	s.lock.Unlock()
+
+	return err == nil, err
+}
+
+func (s *cacheShard) replace(key string, hashedKey uint64, entry []byte) (bool, error) {
+	currentTimestamp := uint64(s.clock.Epoch())
+
+	s.lock.Lock()
+	wrappedEntry, ok := s.getLiveEntryWithoutLock(defaultNamespace, key, hashedKey, currentTimestamp)
+	if !ok {
+		s.lock.Unlock()
+		return false, nil
 	}
+	err := s.setWithoutLock(currentTimestamp, defaultNamespace, key, hashedKey, entry, readExpiryFromEntry(wrappedEntry))
+	s.lock.Unlock()
+
+	return err == nil, err
 }
 
-func (s *cacheShard) setWrappedEntryWithoutLock(ccurrentTimestamp uint64, w []byte, hashedKey uint64) error {
-	if previousIndex := s.hashmap[hashedKey]; previousIndex != 0 {
+func (s *cacheShard) getAndSet(key string, hashedKey uint64, entry []byte) ([]byte, bool, error) {
+	currentTimestamp := uint64(s.clock.Epoch())
+
+	s.lock.Lock()
+	var previous []byte
+	var expiry uint64
+	wrappedEntry, loaded := s.getLiveEntryWithoutLock(defaultNamespace, key, hashedKey, currentTimestamp)
+	if loaded {
+		previous = readEntry(wrappedEntry)
+		expiry = readExpiryFromEntry(wrappedEntry)
+	}
+	err := s.setWithoutLock(currentTimestamp, defaultNamespace, key, hashedKey, entry, expiry)
+	s.lock.Unlock()
+
+	return previous, loaded, err
+}
+
+func (s *cacheShard) getAndDelete(key string, hashedKey uint64) ([]byte, error) {
+	currentTimestamp := uint64(s.clock.Epoch())
+
+	s.lock.Lock()
+	wrappedEntry, ok := s.getLiveEntryWithoutLock(defaultNamespace, key, hashedKey, currentTimestamp)
+	if !ok {
+		s.lock.Unlock()
+		s.delmiss()
+		return nil, ErrEntryNotFound
+	}
+	entry := readEntry(wrappedEntry)
+	s.removeWithoutLock(wrappedEntry, hashedKey, Deleted)
+	s.lock.Unlock()
+
+	s.delhit()
+	return entry, nil
+}
+
+func (s *cacheShard) setWrappedEntryWithoutLock(ccurrentTimestamp uint64, key string, w []byte, hashedKey uint64) error {
+	namespace := readNamespaceFromEntry(w)
+	previousIndex, _ := s.findWithoutLock(namespace, key, hashedKey)
+	collides := s.collidesWithoutLock(namespace, key, hashedKey)
+	if previousIndex != 0 {
 		if previousEntry, err := s.entries.Get(int(previousIndex)); err == nil {
-			resetHashFromEntry(previousEntry)
+			// copies of the entry made by touch and append keep its flags and so its tags
+			if isTaggedEntry(previousEntry) && !isTaggedEntry(w) {
+				s.untagWithoutLock(previousEntry)
+			}
+			s.markDeadWithoutLock(previousEntry)
 		}
 	}
 
@@ -185,43 +386,151 @@ func (s *cacheShard) setWrappedEntryWithoutLock(ccurrentTimestamp uint64, w []by
 	}
 
 	for {
-		if index, err := s.entries.Push(w); err == nil {
-			s.hashmap[hashedKey] = uint64(index)
-			return nil
+		if previousIndex != 0 || !s.isFullWithoutLock() {
+			if index, err := s.entries.Push(w); err == nil {
+				s.indexWithoutLock(w, hashedKey, uint64(index), collides, previousIndex == 0)
+				return nil
+			}
 		}
-		if s.removeOldestEntry(NoSpace) != nil {
+		if s.evictWithoutLock() != nil {
 			return errors.New("entry is bigger than max shard size")
 		}
 	}
 }
 
+// isFullWithoutLock tells whether the shard holds maxEntries entries, so a new key needs an eviction.
+func (s *cacheShard) isFullWithoutLock() bool {
+	return s.maxEntries > 0 && len(s.hashmap)+len(s.collisions) >= s.maxEntries
+}
+
+func (s *cacheShard) compareAndSwap(key string, hashedKey uint64, version uint64, entry []byte) (bool, error) {
+	currentTimestamp := uint64(s.clock.Epoch())
+	s.lock.Lock()
+
+	var currentVersion, expiry uint64
+	if itemIndex, _ := s.findWithoutLock(defaultNamespace, key, hashedKey); itemIndex != 0 {
+		if wrappedEntry, err := s.entries.Get(int(itemIndex)); err == nil {
+			currentVersion, expiry = s.casVersionWithoutLock(wrappedEntry, currentTimestamp)
+		}
+	}
+	if currentVersion != version {
+		s.lock.Unlock()
+		return false, nil
+	}
+
+	w := wrapEntry(currentTimestamp, expiry, s.nextVersion(), defaultNamespace, hashedKey, key, entry, &s.entryBuffer)
+	err := s.setWrappedEntryWithoutLock(currentTimestamp, key, w, hashedKey)
+	s.lock.Unlock()
+
+	return err == nil, err
+}
+
+// casVersionWithoutLock returns the version CompareAndSwap compares with and the expiry it keeps.
+// A key recorded as absent is not present, like GetWithVersion reports it with version 0,
+// and neither is an entry past its explicit expiry.
+func (s *cacheShard) casVersionWithoutLock(wrappedEntry []byte, currentTimestamp uint64) (version uint64, expiry uint64) {
+	if isAbsentEntry(wrappedEntry) {
+		return 0, 0
+	}
+	expiry = readExpiryFromEntry(wrappedEntry)
+	if expiry != 0 && s.isExpired(wrappedEntry, currentTimestamp) {
+		return 0, 0
+	}
+	return readVersionFromEntry(wrappedEntry), expiry
+}
+
+func (s *cacheShard) nextVersion() uint64 {
+	s.version++
+	return s.version
+}
+
 func (s *cacheShard) append(key string, hashedKey uint64, entry []byte) error {
 	s.lock.Lock()
-	wrappedEntry, err := s.getValidWrapEntry(key, hashedKey)
+	err := s.appendWithoutLock(uint64(s.clock.Epoch()), key, hashedKey, entry)
+	s.lock.Unlock()
 
+	return err
+}
+
+func (s *cacheShard) appendWithoutLock(currentTimestamp uint64, key string, hashedKey uint64, entry []byte) error {
+	wrappedEntry, err := s.getValidWrapEntry(key, hashedKey)
 	if err == ErrEntryNotFound {
-		err = s.addNewWithoutLock(key, hashedKey, entry)
-		s.lock.Unlock()
-		return err
+		return s.setWithoutLock(currentTimestamp, defaultNamespace, key, hashedKey, entry, 0)
 	}
 	if err != nil {
-		s.lock.Unlock()
 		return err
 	}
 
-	currentTimestamp := uint64(s.clock.Epoch())
 	w := appendToWrappedEntry(currentTimestamp, wrappedEntry, entry, &s.entryBuffer)
-	err = s.setWrappedEntryWithoutLock(currentTimestamp, w, hashedKey)
+	writeVersionToEntry(w, s.nextVersion())
+	return s.setWrappedEntryWithoutLock(currentTimestamp, key, w, hashedKey)
+}
 
-	s.lock.Unlock()
+// keyState is what a write going through Config.Store reads of its key before calling the Store.
+type keyState struct {
+	// version of the entry stored for the key, 0 when there is none
+	version uint64
+	// live tells whether the entry holds a value and has not expired
+	live bool
+	// value is a copy of the value of the entry, expired or not, when it was asked for
+	value []byte
+	// casVersion and casExpiry are the version CompareAndSwap compares with and the expiry it keeps,
+	// casExpiry is also the expiry Replace keeps for a live entry
+	casVersion uint64
+	casExpiry  uint64
+}
 
-	return err
+// readKey reads the state of the key of the default namespace under the read lock, withValue copies its value.
+func (s *cacheShard) readKey(key string, hashedKey uint64, withValue bool) (state keyState) {
+	currentTimestamp := uint64(s.clock.Epoch())
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	index, _ := s.findWithoutLock(defaultNamespace, key, hashedKey)
+	if index == 0 {
+		return state
+	}
+	wrappedEntry, err := s.entries.Get(int(index))
+	if err != nil {
+		return state
+	}
+	state.version = readVersionFromEntry(wrappedEntry)
+	state.casVersion, state.casExpiry = s.casVersionWithoutLock(wrappedEntry, currentTimestamp)
+	if isAbsentEntry(wrappedEntry) {
+		return state
+	}
+	state.live = !s.isExpired(wrappedEntry, currentTimestamp)
+	if withValue {
+		state.value = readEntry(wrappedEntry)
+	}
+	return state
+}
+
+// writeIfVersion calls write under the write lock if the key still has the version readKey returned
+// before the write reached Config.Store. Otherwise a write bypassing the Store, a load or SetAbsent,
+// raced with it, so the key is removed and the next read loads what the Store holds.
+func (s *cacheShard) writeIfVersion(key string, hashedKey uint64, version uint64, write func(currentTimestamp uint64) error) error {
+	currentTimestamp := uint64(s.clock.Epoch())
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var wrappedEntry []byte
+	if index, _ := s.findWithoutLock(defaultNamespace, key, hashedKey); index != 0 {
+		wrappedEntry, _ = s.entries.Get(int(index))
+	}
+	if wrappedEntry == nil && version == 0 || wrappedEntry != nil && readVersionFromEntry(wrappedEntry) == version {
+		return write(currentTimestamp)
+	}
+	if wrappedEntry != nil {
+		s.removeWithoutLock(wrappedEntry, hashedKey, Deleted)
+	}
+	return nil
 }
 
-func (s *cacheShard) del(hashedKey uint64) error {
+func (s *cacheShard) del(namespace uint32, key string, hashedKey uint64) error {
 	s.lock.RLock()
 	{
-		itemIndex := s.hashmap[hashedKey]
+		itemIndex, _ := s.findWithoutLock(namespace, key, hashedKey)
 
 		if itemIndex == 0 {
 			s.lock.RUnlock()
@@ -229,6 +538,24 @@ func (s *cacheShard) del(hashedKey uint64) error {
 			return ErrEntryNotFound
 		}
 
+		if err := s.entries.CheckGet(int(itemIndex)); err != nil {
+			s.lock.RUnlock()
+			s.delmiss()
+			return err
+		}
+	}
+	s.lock.RUnlock()
+
+	s.lock.Lock()
+	{
+		itemIndex, _ := s.findWithoutLock(namespace, key, hashedKey)
+
+		if itemIndex == 0 {
+			s.lock.Unlock()
+			s.delmiss()
+			return ErrEntryNotFound
+		}
+
 		wrappedEntry, err := s.entries.Get(int(itemIndex))
 		if err != nil {
 			s.lock.Unlock()
@@ -236,12 +563,7 @@ func (s *cacheShard) del(hashedKey uint64) error {
 			return err
 		}
 
-		delete(s.hashmap, hashedKey)
-		s.onRemove(wrappedEntry, Deleted)
-		if s.statsEnabled {
-			delete(s.hashmapStats, hashedKey)
-		}
-		resetHashFromEntry(wrappedEntry)
+		s.removeWithoutLock(wrappedEntry, hashedKey, Deleted)
 	}
 	s.lock.Unlock()
 
@@ -249,6 +571,63 @@ func (s *cacheShard) del(hashedKey uint64) error {
 	return nil
 }
 
+// delPrefix removes entries of the namespace with keys starting with the prefix.
+// Entries recording absent keys are dropped too but not counted.
+func (s *cacheShard) delPrefix(namespace uint32, prefix string) int {
+	s.lock.Lock()
+	removed := 0
+	s.forEachIndexWithoutLock(func(hashedKey uint64, index uint64) {
+		wrappedEntry, err := s.entries.Get(int(index))
+		if err != nil || readNamespaceFromEntry(wrappedEntry) != namespace || !hasKeyPrefix(wrappedEntry, prefix) {
+			return
+		}
+
+		if !isAbsentEntry(wrappedEntry) {
+			removed++
+		}
+		s.removeWithoutLock(wrappedEntry, hashedKey, Deleted)
+	})
+	s.lock.Unlock()
+
+	atomic.AddInt64(&s.stats.DelHits, int64(removed))
+	return removed
+}
+
+// keysWithPrefix returns references to the entries of the namespace with keys starting with the prefix.
+func (s *cacheShard) keysWithPrefix(namespace uint32, prefix string) []entryRef {
+	var refs []entryRef
+	s.lock.RLock()
+	s.forEachIndexWithoutLock(func(hashedKey uint64, index uint64) {
+		wrappedEntry, err := s.entries.Get(int(index))
+		if err != nil || readNamespaceFromEntry(wrappedEntry) != namespace || !hasKeyPrefix(wrappedEntry, prefix) {
+			return
+		}
+		refs = append(refs, entryRef{hashedKey: hashedKey, key: entryCollisionKey(wrappedEntry)})
+	})
+	s.lock.RUnlock()
+	return refs
+}
+
+// removeKey removes the entry stored for the key of the reference, like delPrefix it reports
+// whether it held a value rather than recording an absent key.
+func (s *cacheShard) removeKey(ref entryRef, reason RemoveReason) bool {
+	s.lock.Lock()
+	index, _ := s.findWithoutLock(ref.key.namespace, ref.key.key, ref.hashedKey)
+	wrappedEntry, err := s.entries.Get(int(index))
+	if index == 0 || err != nil {
+		s.lock.Unlock()
+		return false
+	}
+	removed := !isAbsentEntry(wrappedEntry)
+	s.removeWithoutLock(wrappedEntry, ref.hashedKey, reason)
+	s.lock.Unlock()
+
+	if removed && reason == Deleted {
+		s.delhit()
+	}
+	return removed
+}
+
 func (s *cacheShard) onEvict(oldestEntry []byte, currentTimestamp uint64, evict func(reason RemoveReason) error) bool {
 	if s.isExpired(oldestEntry, currentTimestamp) {
 		evict(Expried)
@@ -257,7 +636,21 @@ func (s *cacheShard) onEvict(oldestEntry []byte, currentTimestamp uint64, evict
 	return false
 }
 
+// isExpired tells whether the entry is past its expiry or LifeWindow. Blocks with a zero hash,
+// dead entries and the fillers pushed by the queue, are always expired.
 func (s *cacheShard) isExpired(oldestEntry []byte, currentTimestamp uint64) bool {
+	if readHashFromEntry(oldestEntry) == 0 {
+		return true
+	}
+	if expiry := readExpiryFromEntry(oldestEntry); expiry != 0 {
+		if currentTimestamp > expiry {
+			return true
+		}
+		// with idle expiration the expiry is a hard limit on top of the idle window
+		if !s.idleExpiration {
+			return false
+		}
+	}
 	oldestTimestamp := readTimestampFromEntry(oldestEntry)
 	if currentTimestamp <= oldestTimestamp {
 		return false
@@ -265,55 +658,140 @@ func (s *cacheShard) isExpired(oldestEntry []byte, currentTimestamp uint64) bool
 	return currentTimestamp-oldestTimestamp > s.lifeWindow
 }
 
+// cleanUp drops the entries whose explicit expiry has passed, then pops expired entries from the head.
 func (s *cacheShard) cleanUp(currentTimestamp uint64) {
 	s.lock.Lock()
+	s.removeExpiredWithoutLock(currentTimestamp)
 	for {
-		if oldestEntry, err := s.entries.Peek(); err != nil {
+		oldestEntry, err := s.entries.Peek()
+		if err != nil {
 			break
-		} else if evicted := s.onEvict(oldestEntry, currentTimestamp, s.removeOldestEntry); !evicted {
+		}
+		if s.onEvict(oldestEntry, currentTimestamp, s.removeOldestEntry) {
+			continue
+		}
+		if !s.outlivesLifeWindow(oldestEntry, currentTimestamp) {
 			break
 		}
+		s.renewOldestWithoutLock(oldestEntry, currentTimestamp)
 	}
 	s.lock.Unlock()
 }
 
-func (s *cacheShard) getEntry(hashedKey uint64) ([]byte, error) {
-	s.lock.RLock()
+// outlivesLifeWindow tells whether an entry kept by its explicit expiry is older than the LifeWindow.
+// Left at the head, it would stop cleanUp from reaching the expired entries behind it.
+func (s *cacheShard) outlivesLifeWindow(wrappedEntry []byte, currentTimestamp uint64) bool {
+	if s.idleExpiration || readExpiryFromEntry(wrappedEntry) == 0 {
+		return false
+	}
+	timestamp := readTimestampFromEntry(wrappedEntry)
+	return currentTimestamp > timestamp && currentTimestamp-timestamp > s.lifeWindow
+}
 
-	entry, err := s.getWrappedEntry(hashedKey)
-	newEntry := make([]byte, len(entry))
-	copy(newEntry, entry)
+// renewOldestWithoutLock moves the oldest entry to the tail with a fresh timestamp.
+// Its explicit expiry still decides when it expires.
+func (s *cacheShard) renewOldestWithoutLock(oldest []byte, currentTimestamp uint64) {
+	w := s.copyToEvictionBufferWithoutLock(oldest)
+	writeTimestampToEntry(w, currentTimestamp)
+	s.requeueOldestWithoutLock(w, readHashFromEntry(w))
+}
 
-	s.lock.RUnlock()
-	return newEntry, err
+func (s *cacheShard) expireAt(key string, hashedKey uint64, expiry uint64) error {
+	s.lock.Lock()
+	wrappedEntry, err := s.getWrappedEntryByKey(defaultNamespace, key, hashedKey)
+	if err != nil {
+		s.lock.Unlock()
+		return err
+	}
+
+	writeExpiryToEntry(wrappedEntry, expiry)
+	s.trackExpiryWithoutLock(wrappedEntry, hashedKey)
+	s.lock.Unlock()
+	return nil
 }
 
-func (s *cacheShard) copyHashedKeys() (keys []uint64, next int) {
+// touch takes the write lock only when the entry has to be moved, so reads of entries
+// already touched within the current second do not serialize on it.
+func (s *cacheShard) touch(namespace uint32, key string, hashedKey uint64, currentTimestamp uint64) error {
 	s.lock.RLock()
-	keys = make([]uint64, len(s.hashmap))
-
-	for key := range s.hashmap {
-		keys[next] = key
-		next++
+	wrappedEntry, ok := s.getLiveEntryWithoutLock(namespace, key, hashedKey, currentTimestamp)
+	touched := ok && readTimestampFromEntry(wrappedEntry) >= currentTimestamp
+	s.lock.RUnlock()
+	if !ok {
+		return ErrEntryNotFound
+	}
+	if touched {
+		return nil
 	}
 
+	s.lock.Lock()
+	err := s.touchWithoutLock(namespace, key, hashedKey, currentTimestamp)
 	s.lock.Unlock()
-	return keys, next
+	return err
+}
+
+// touchWithoutLock moves the entry to the tail of the queue with a fresh timestamp,
+// keeping the queue ordered by timestamp for the head scan done by cleanUp.
+// Entries already touched within the current second are left in place.
+func (s *cacheShard) touchWithoutLock(namespace uint32, key string, hashedKey uint64, currentTimestamp uint64) error {
+	wrappedEntry, ok := s.getLiveEntryWithoutLock(namespace, key, hashedKey, currentTimestamp)
+	if !ok {
+		return ErrEntryNotFound
+	}
+	if readTimestampFromEntry(wrappedEntry) >= currentTimestamp {
+		return nil
+	}
+
+	w := appendToWrappedEntry(currentTimestamp, wrappedEntry, nil, &s.entryBuffer)
+	return s.setWrappedEntryWithoutLock(currentTimestamp, key, w, hashedKey)
+}
+
+func (s *cacheShard) removeWithoutLock(wrappedEntry []byte, hashedKey uint64, reason RemoveReason) {
+	s.unindexWithoutLock(wrappedEntry, hashedKey)
+	if isTaggedEntry(wrappedEntry) {
+		s.untagWithoutLock(wrappedEntry)
+	}
+	if !isAbsentEntry(wrappedEntry) {
+		s.onRemove(wrappedEntry, reason)
+	}
+	if s.statsEnabled {
+		delete(s.hashmapStats, hashedKey)
+	}
+	delete(s.accesses, hashedKey)
+	s.markDeadWithoutLock(wrappedEntry)
+}
+
+func (s *cacheShard) getEntry(ref entryRef) ([]byte, error) {
+	s.lock.RLock()
+
+	entry, err := s.getEntryByRefWithoutLock(ref)
+	newEntry := make([]byte, len(entry))
+	copy(newEntry, entry)
+
+	s.lock.RUnlock()
+	return newEntry, err
 }
 
 func (s *cacheShard) removeOldestEntry(reason RemoveReason) error {
 	oldest, err := s.entries.Pop()
-	if err != nil {
+	if err == nil {
 		hash := readHashFromEntry(oldest)
 		if hash == 0 {
+			s.releaseDeadWithoutLock(oldest)
 			return nil
 		}
 
-		delete(s.hashmap, hash)
-		s.onRemove(oldest, reason)
+		s.unindexWithoutLock(oldest, hash)
+		if isTaggedEntry(oldest) {
+			s.untagWithoutLock(oldest)
+		}
+		if !isAbsentEntry(oldest) {
+			s.onRemove(oldest, reason)
+		}
 		if s.statsEnabled {
 			delete(s.hashmapStats, hash)
 		}
+		delete(s.accesses, hash)
 		return nil
 	}
 	return err
@@ -322,7 +800,40 @@ func (s *cacheShard) removeOldestEntry(reason RemoveReason) error {
 func (s *cacheShard) reset(config Config) {
 	s.lock.Lock()
 	s.hashmap = make(map[uint64]uint64, config.initialShardSize())
+	s.collisions = make(map[collisionKey]uint64)
 	s.entries.Reset()
+	s.deadBytes = 0
+	s.expiries = nil
+	s.tags = make(map[string]map[collisionKey]uint64)
+	s.entryTags = make(map[collisionKey][]string)
+	if s.accesses != nil {
+		s.accesses = make(map[uint64]uint32)
+	}
+	if s.admission != nil {
+		s.admission.reset()
+	}
+	s.lock.Unlock()
+}
+
+// resetNamespace drops every entry of the namespace without calling the callbacks, like reset.
+func (s *cacheShard) resetNamespace(namespace uint32) {
+	s.lock.Lock()
+	s.forEachIndexWithoutLock(func(hashedKey uint64, index uint64) {
+		wrappedEntry, err := s.entries.Get(int(index))
+		if err != nil || readNamespaceFromEntry(wrappedEntry) != namespace {
+			return
+		}
+
+		s.unindexWithoutLock(wrappedEntry, hashedKey)
+		if isTaggedEntry(wrappedEntry) {
+			s.untagWithoutLock(wrappedEntry)
+		}
+		if s.statsEnabled {
+			delete(s.hashmapStats, hashedKey)
+		}
+		delete(s.accesses, hashedKey)
+		s.markDeadWithoutLock(wrappedEntry)
+	})
 	s.lock.Unlock()
 }
 
@@ -334,7 +845,19 @@ func (s *cacheShard) resetStats() {
 
 func (s *cacheShard) len() int {
 	s.lock.RLock()
-	res := len(s.hashmap)
+	res := len(s.hashmap) + len(s.collisions)
+	s.lock.RUnlock()
+	return res
+}
+
+func (s *cacheShard) namespaceLen(namespace uint32) int {
+	s.lock.RLock()
+	res := 0
+	s.forEachIndexWithoutLock(func(_ uint64, index uint64) {
+		if wrappedEntry, err := s.entries.Get(int(index)); err == nil && readNamespaceFromEntry(wrappedEntry) == namespace {
+			res++
+		}
+	})
 	s.lock.RUnlock()
 	return res
 }
@@ -351,8 +874,13 @@ func (s *cacheShard) GetStats() Stats {
 		Hits:      atomic.LoadInt64(&s.stats.Hits),
 		Misses:    atomic.LoadInt64(&s.stats.Misses),
 		DelHits:   atomic.LoadInt64(&s.stats.DelHits),
-		DelMissed: atomic.LoadInt64(&s.stats.Misses),
+		DelMissed: atomic.LoadInt64(&s.stats.DelMissed),
 		Collision: atomic.LoadInt64(&s.stats.Collision),
+
+		NegativeHits: atomic.LoadInt64(&s.stats.NegativeHits),
+		Admitted:     atomic.LoadInt64(&s.stats.Admitted),
+		Rejected:     atomic.LoadInt64(&s.stats.Rejected),
+		MaxEntries:   int64(s.maxEntries),
 	}
 	return stats
 }
@@ -374,22 +902,33 @@ func (s *cacheShard) getKeyMetadata(key uint64) Metadata {
 
 func (s *cacheShard) hit(key uint64) {
 	atomic.AddInt64(&s.stats.Hits, 1)
-	if s.statsEnabled {
+	if s.statsEnabled || s.accesses != nil {
 		s.lock.Lock()
-		s.hashmapStats[key]++
+		s.recordAccessWithoutLock(key)
 		s.lock.Unlock()
 	}
 }
 
 func (s *cacheShard) hitWithoutLock(key uint64) {
 	atomic.AddInt64(&s.stats.Hits, 1)
+	s.recordAccessWithoutLock(key)
+}
+
+func (s *cacheShard) recordAccessWithoutLock(key uint64) {
 	if s.statsEnabled {
 		s.hashmapStats[key]++
 	}
+	if s.accesses != nil {
+		s.recordEvictionAccessWithoutLock(key)
+	}
 }
 
 func (s *cacheShard) miss() {
-	atomic.AddInt64(&s.stats.Hits, 1)
+	atomic.AddInt64(&s.stats.Misses, 1)
+}
+
+func (s *cacheShard) negativeHit() {
+	atomic.AddInt64(&s.stats.NegativeHits, 1)
 }
 
 func (s *cacheShard) delhit() {
@@ -397,7 +936,7 @@ func (s *cacheShard) delhit() {
 }
 
 func (s *cacheShard) delmiss() {
-	atomic.AddInt64(&s.stats.DelHits, 1)
+	atomic.AddInt64(&s.stats.DelMissed, 1)
 }
 
 func (s *cacheShard) collision() {
@@ -413,10 +952,20 @@ func initNewShard(config Config, callback onRemoveCallBack, clock clock) *cacheS
 
 	return &cacheShard{
 		hashmap:      make(map[uint64]uint64, config.initialShardSize()),
+		collisions:   make(map[collisionKey]uint64),
 		hashmapStats: make(map[uint64]uint32, config.initialShardSize()),
 		entries:      *queue.NewBytesQueue(bytesQueueInitialCapacity, maximumShardSizeInBytes, config.Verbose),
 		entryBuffer:  make([]byte, config.maximumShardSizeInBytes()),
 		onRemove:     callback,
+		loads:        make(map[uint64]*loadCall),
+		tags:         make(map[string]map[collisionKey]uint64),
+		entryTags:    make(map[collisionKey][]string),
+		policy:       config.EvictionPolicy,
+		accesses:     newAccesses(config.EvictionPolicy),
+		admission:    newFrequencySketch(config.AdmissionPolicy, config.initialShardSize()),
+		maxEntries:   config.maximumEntriesInShard(),
+
+		initialCapacity: bytesQueueInitialCapacity,
 
 		isVerbose:    config.Verbose,
 		logger:       newLogger(config.Logger),
@@ -424,5 +973,9 @@ func initNewShard(config Config, callback onRemoveCallBack, clock clock) *cacheS
 		lifeWindow:   uint64(config.LifeWindow.Seconds()),
 		statsEnabled: config.StatsEnabled,
 		cleanEnabled: config.CleanWindow > 0,
+
+		negativeLifeWindow: uint64(config.NegativeLifeWindow.Seconds()),
+		idleExpiration:     config.IdleExpiration,
+		maxAge:             config.maxAgeInSeconds(),
 	}
 }
@@ -0,0 +1,51 @@
+package largecache
+
+import "context"
+
+// GetStale reads entry for the key like GetOrLoad, but an expired entry which was not removed yet
+// is returned immediately while refresh reloads it in the background.
+// Only one refresh runs per key and at most Config.MaxConcurrentRefreshes run at once,
+// further expired reads return the stale entry without starting another one.
+// Refresh errors are reported to the Logger and leave the stale entry in place.
+func (c *LargeCache) GetStale(ctx context.Context, key string, refresh Loader) ([]byte, error) {
+	hashedKey := c.hash.Sum64(key)
+	shard := c.getShard(hashedKey)
+	entry, resp, err := shard.getWithInfo(key, hashedKey)
+	if err == ErrKnownAbsent {
+		return nil, err
+	}
+	if err != nil {
+		return shard.load(ctx, key, hashedKey, refresh)
+	}
+
+	if resp.EntryStatus == Expried {
+		c.refreshInBackground(context.WithoutCancel(ctx), shard, key, hashedKey, refresh)
+	}
+	return entry, nil
+}
+
+func (c *LargeCache) refreshInBackground(ctx context.Context, shard *cacheShard, key string, hashedKey uint64, refresh Loader) {
+	select {
+	case c.refreshes <- struct{}{}:
+	default:
+		return
+	}
+
+	call, ok := shard.tryStartLoad(key, hashedKey)
+	if !ok {
+		<-c.refreshes
+		return
+	}
+
+	go func() {
+		defer func() {
+			shard.finishLoad(hashedKey, call)
+			<-c.refreshes
+		}()
+
+		call.entry, call.err = shard.loadAndSet(ctx, key, hashedKey, refresh)
+		if call.err != nil {
+			c.logger.Printf("Refreshing %q failed: %s", key, call.err)
+		}
+	}()
+}
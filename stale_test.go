@@ -0,0 +1,93 @@
+package largecache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetStale(t *testing.T) {
+	t.Parallel()
+
+	clock := mockedClock{value: 0}
+	cache, _ := newLargeCache(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntriesSize:     256,
+	}, &clock)
+	defer cache.Close()
+
+	value, err := cache.GetStale(context.Background(), "key", func(ctx context.Context) ([]byte, error) {
+		return []byte("value"), nil
+	})
+	noError(t, err)
+	assertEqual(t, []byte("value"), value)
+
+	clock.set(10)
+	var calls int32
+	release := make(chan struct{})
+	refresh := func(ctx context.Context) ([]byte, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return []byte("fresh"), nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := cache.GetStale(context.Background(), "key", refresh)
+			noError(t, err)
+			assertEqual(t, []byte("value"), value)
+		}()
+	}
+	wg.Wait()
+	close(release)
+
+	for i := 0; i < 100 && len(cache.refreshes) > 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	assertEqual(t, int32(1), atomic.LoadInt32(&calls))
+	value, err = cache.Get("key")
+	noError(t, err)
+	assertEqual(t, []byte("fresh"), value)
+}
+
+func TestGetStaleRefreshError(t *testing.T) {
+	t.Parallel()
+
+	clock := mockedClock{value: 0}
+	logger := make(chanLogger, 1)
+	cache, _ := newLargeCache(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntriesSize:     256,
+		Logger:             logger,
+	}, &clock)
+	defer cache.Close()
+	cache.Set("key", []byte("value"))
+
+	clock.set(10)
+	value, err := cache.GetStale(context.Background(), "key", func(ctx context.Context) ([]byte, error) {
+		return nil, errors.New("refresh failed")
+	})
+	noError(t, err)
+	assertEqual(t, []byte("value"), value)
+
+	assertEqual(t, "Refreshing %q failed: %s", <-logger)
+	value, _ = cache.Get("key")
+	assertEqual(t, []byte("value"), value)
+}
+
+type chanLogger chan string
+
+func (l chanLogger) Printf(format string, v ...interface{}) {
+	l <- format
+}
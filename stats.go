@@ -12,4 +12,12 @@ type Stats struct {
 	DelMissed int64 `json:"delete_misses"`
 	// Collisions is a number of happend key-collisions
 	Collision int64 `json:"collisions"`
+	// NegativeHits is a number of reads of keys recorded as absent
+	NegativeHits int64 `json:"negative_hits"`
+	// Admitted is a number of new keys allowed by the admission policy to evict an entry
+	Admitted int64 `json:"admitted"`
+	// Rejected is a number of new keys dropped by the admission policy instead of evicting an entry
+	Rejected int64 `json:"rejected"`
+	// MaxEntries is the max number of entries in the cache, 0 when it is not limited
+	MaxEntries int64 `json:"max_entries"`
 }
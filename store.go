@@ -0,0 +1,293 @@
+package largecache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const defaultWriteBehindInterval = time.Second
+
+// Store is a backing store kept in sync with the cache when set in Config.
+type Store interface {
+	// Load returns the entry for the key or ErrKnownAbsent when the key does not exist.
+	Load(ctx context.Context, key string) ([]byte, error)
+	Save(ctx context.Context, key string, entry []byte) error
+	Delete(ctx context.Context, key string) error
+}
+
+// StoreMode selects how writes to the cache reach the Store.
+type StoreMode int
+
+const (
+	// WriteThrough saves to the Store before the cache and fails the write when the Store does.
+	// Writes of a key wait for each other while the Store saves, so both end up with the same entry.
+	WriteThrough StoreMode = iota
+	// WriteBehind queues writes and saves them to the Store in the background.
+	WriteBehind
+)
+
+// MemoryStore is a Store keeping entries in a map, meant as a reference and for tests.
+type MemoryStore struct {
+	lock    sync.RWMutex
+	entries map[string][]byte
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entries: make(map[string][]byte),
+	}
+}
+
+func (s *MemoryStore) Load(ctx context.Context, key string) ([]byte, error) {
+	s.lock.RLock()
+	entry, ok := s.entries[key]
+	s.lock.RUnlock()
+	if !ok {
+		return nil, ErrKnownAbsent
+	}
+	return append([]byte(nil), entry...), nil
+}
+
+func (s *MemoryStore) Save(ctx context.Context, key string, entry []byte) error {
+	s.lock.Lock()
+	s.entries[key] = append([]byte(nil), entry...)
+	s.lock.Unlock()
+	return nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+	s.lock.Lock()
+	delete(s.entries, key)
+	s.lock.Unlock()
+	return nil
+}
+
+// GetThrough reads entry for the key like GetOrLoad, loading missing keys from Config.Store.
+func (c *LargeCache) GetThrough(ctx context.Context, key string) ([]byte, error) {
+	if c.config.Store == nil {
+		return nil, errors.New("Store is not configured")
+	}
+	return c.GetOrLoad(ctx, key, func(ctx context.Context) ([]byte, error) {
+		return c.config.Store.Load(ctx, key)
+	})
+}
+
+// cacheStore propagates writes to Config.Store. A write holds the lock of its key from the Store call
+// until the shard applies it, so the Store sees the writes of a key in the order the cache applies them.
+// Shard locks are not held during Store calls, reads and writes of other keys go on meanwhile.
+type cacheStore struct {
+	ctx         context.Context
+	timeout     time.Duration
+	store       Store
+	writeBehind *writeBehind
+
+	lock  sync.Mutex
+	locks map[string]*keyLock
+}
+
+// keyLock orders the writes of a key, holders counts the writes holding or waiting for it
+type keyLock struct {
+	sync.Mutex
+	holders int
+}
+
+func newCacheStore(ctx context.Context, config Config, writeBehind *writeBehind) *cacheStore {
+	if config.Store == nil {
+		return nil
+	}
+	return &cacheStore{
+		ctx:         ctx,
+		timeout:     config.StoreTimeout,
+		store:       config.Store,
+		writeBehind: writeBehind,
+		locks:       make(map[string]*keyLock),
+	}
+}
+
+// lockKey waits for the writes of the key in progress and returns the function releasing the key.
+func (s *cacheStore) lockKey(key string) func() {
+	s.lock.Lock()
+	l, ok := s.locks[key]
+	if !ok {
+		l = &keyLock{}
+		s.locks[key] = l
+	}
+	l.holders++
+	s.lock.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+		s.lock.Lock()
+		l.holders--
+		if l.holders == 0 {
+			delete(s.locks, key)
+		}
+		s.lock.Unlock()
+	}
+}
+
+func (s *cacheStore) save(key string, entry []byte) error {
+	if s.writeBehind != nil {
+		return s.writeBehind.enqueue(key, entry, false)
+	}
+	ctx, cancel := s.context()
+	defer cancel()
+	return s.store.Save(ctx, key, entry)
+}
+
+func (s *cacheStore) delete(key string) error {
+	if s.writeBehind != nil {
+		return s.writeBehind.enqueue(key, nil, true)
+	}
+	ctx, cancel := s.context()
+	defer cancel()
+	return s.store.Delete(ctx, key)
+}
+
+// context bounds a WriteThrough call by StoreTimeout.
+func (s *cacheStore) context() (context.Context, context.CancelFunc) {
+	if s.timeout > 0 {
+		return context.WithTimeout(s.ctx, s.timeout)
+	}
+	return s.ctx, func() {}
+}
+
+type storeMutation struct {
+	key      string
+	entry    []byte
+	deleted  bool
+	attempts int
+}
+
+// writeBehind collects mutations and applies them to the store in batches.
+// Only the latest mutation of a key is kept while it waits for the flush.
+type writeBehind struct {
+	store     Store
+	batchSize int
+	retries   int
+	onError   func(key string, err error)
+
+	lock    sync.Mutex
+	pending map[string]*storeMutation
+	// stopped is set once run does its final flush, mutations are refused from then on
+	stopped bool
+	full    chan struct{}
+	done    chan struct{}
+	err     error
+}
+
+func newWriteBehind(config Config, logger Logger) *writeBehind {
+	onError := config.OnStoreError
+	if onError == nil {
+		onError = func(key string, err error) {
+			logger.Printf("Saving %q to store failed: %s", key, err)
+		}
+	}
+
+	return &writeBehind{
+		store:     config.Store,
+		batchSize: config.WriteBehindBatchSize,
+		retries:   config.WriteBehindRetries,
+		onError:   onError,
+		pending:   make(map[string]*storeMutation),
+		full:      make(chan struct{}, 1),
+		done:      make(chan struct{}),
+	}
+}
+
+func (w *writeBehind) enqueue(key string, entry []byte, deleted bool) error {
+	m := &storeMutation{key: key, deleted: deleted}
+	if !deleted {
+		m.entry = append([]byte(nil), entry...)
+	}
+
+	w.lock.Lock()
+	if w.stopped {
+		w.lock.Unlock()
+		return ErrStoreClosed
+	}
+	w.pending[key] = m
+	full := w.batchSize > 0 && len(w.pending) >= w.batchSize
+	w.lock.Unlock()
+
+	if full {
+		select {
+		case w.full <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+func (w *writeBehind) run(ctx context.Context, interval time.Duration, closed <-chan struct{}) {
+	defer close(w.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			w.flush(ctx, false)
+		case <-w.full:
+			w.flush(ctx, false)
+		case <-ctx.Done():
+			w.stop()
+			w.err = w.flush(context.WithoutCancel(ctx), true)
+			return
+		case <-closed:
+			w.stop()
+			w.err = w.flush(ctx, true)
+			return
+		}
+	}
+}
+
+// stop refuses further mutations, the ones already pending are left for the final flush.
+func (w *writeBehind) stop() {
+	w.lock.Lock()
+	w.stopped = true
+	w.lock.Unlock()
+}
+
+// flush applies pending mutations. Failed ones are queued again for the next flush
+// until they run out of retries, the final flush retries them right away.
+func (w *writeBehind) flush(ctx context.Context, final bool) error {
+	w.lock.Lock()
+	batch := w.pending
+	w.pending = make(map[string]*storeMutation, len(batch))
+	w.lock.Unlock()
+
+	var errs []error
+	for key, m := range batch {
+		err := w.apply(ctx, m)
+		for final && err != nil && m.attempts <= w.retries {
+			err = w.apply(ctx, m)
+		}
+		if err == nil {
+			continue
+		}
+
+		if !final && m.attempts <= w.retries {
+			w.lock.Lock()
+			if _, ok := w.pending[key]; !ok {
+				w.pending[key] = m
+			}
+			w.lock.Unlock()
+			continue
+		}
+		w.onError(key, err)
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+func (w *writeBehind) apply(ctx context.Context, m *storeMutation) error {
+	m.attempts++
+	if m.deleted {
+		return w.store.Delete(ctx, m.key)
+	}
+	return w.store.Save(ctx, m.key, m.entry)
+}
@@ -0,0 +1,501 @@
+package largecache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type failingStore struct {
+	*MemoryStore
+	failures int
+	lock     sync.Mutex
+}
+
+func (s *failingStore) Save(ctx context.Context, key string, entry []byte) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("store unavailable")
+	}
+	return s.MemoryStore.Save(ctx, key, entry)
+}
+
+func TestWriteThroughStore(t *testing.T) {
+	t.Parallel()
+
+	// given
+	store := NewMemoryStore()
+	config := Config{
+		Shards:             8,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1000,
+		MaxEntriesSize:     256,
+	}
+	config.Store = store
+	cache, _ := New(context.Background(), config)
+	defer cache.Close()
+
+	// when
+	noError(t, cache.Set("key", []byte("value")))
+	stored, err := store.Load(context.Background(), "key")
+
+	// then
+	noError(t, err)
+	assertEqual(t, []byte("value"), stored)
+
+	// when
+	noError(t, cache.Delete("key"))
+	_, err = store.Load(context.Background(), "key")
+
+	// then
+	assertEqual(t, ErrKnownAbsent, err)
+}
+
+type hookedStore struct {
+	*MemoryStore
+	afterSave func(key string, entry []byte)
+}
+
+func (s *hookedStore) Save(ctx context.Context, key string, entry []byte) error {
+	err := s.MemoryStore.Save(ctx, key, entry)
+	s.afterSave(key, entry)
+	return err
+}
+
+func TestWriteThroughStoreKeepsOrderOfConcurrentWrites(t *testing.T) {
+	t.Parallel()
+
+	// given
+	store := &hookedStore{MemoryStore: NewMemoryStore()}
+	cache, _ := New(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntriesSize:     256,
+		Store:              store,
+	})
+	defer cache.Close()
+	done := make(chan error, 1)
+	store.afterSave = func(key string, entry []byte) {
+		if string(entry) != "first" {
+			return
+		}
+		// a concurrent write of the key arrives between the save and the cache write
+		go func() {
+			done <- cache.Set("key", []byte("second"))
+		}()
+		select {
+		case err := <-done:
+			done <- err
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	// when
+	noError(t, cache.Set("key", []byte("first")))
+	noError(t, <-done)
+
+	// then
+	cached, err := cache.Get("key")
+	noError(t, err)
+	stored, err := store.Load(context.Background(), "key")
+	noError(t, err)
+	assertEqual(t, stored, cached)
+}
+
+func TestWriteThroughStoreDropsKeyLoadedDuringConditionalWrite(t *testing.T) {
+	t.Parallel()
+
+	// given
+	store := &hookedStore{MemoryStore: NewMemoryStore()}
+	cache, _ := New(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntriesSize:     256,
+		Store:              store,
+	})
+	defer cache.Close()
+	store.afterSave = func(key string, entry []byte) {
+		// a load bypassing the Store caches the key between the save and the cache write
+		cache.GetOrLoad(context.Background(), key, func(ctx context.Context) ([]byte, error) {
+			return []byte("loaded"), nil
+		})
+	}
+
+	// when
+	saved, err := cache.SetIfAbsent("key", []byte("value"))
+
+	// then
+	noError(t, err)
+	assertEqual(t, true, saved)
+	_, err = cache.Get("key")
+	assertEqual(t, ErrEntryNotFound, err)
+	stored, err := store.Load(context.Background(), "key")
+	noError(t, err)
+	assertEqual(t, []byte("value"), stored)
+}
+
+func TestWriteThroughStoreError(t *testing.T) {
+	t.Parallel()
+
+	// given
+	config := Config{
+		Shards:             8,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1000,
+		MaxEntriesSize:     256,
+	}
+	config.Store = &failingStore{MemoryStore: NewMemoryStore(), failures: 1}
+	cache, _ := New(context.Background(), config)
+	defer cache.Close()
+
+	// when
+	err := cache.Set("key", []byte("value"))
+	_, getErr := cache.Get("key")
+
+	// then
+	assertEqual(t, "store unavailable", err.Error())
+	assertEqual(t, ErrEntryNotFound, getErr)
+}
+
+// blockingStore blocks saves of the entry "blocked" until release is closed or the context is done
+type blockingStore struct {
+	*MemoryStore
+	saving  chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingStore) Save(ctx context.Context, key string, entry []byte) error {
+	if string(entry) == "blocked" {
+		s.saving <- struct{}{}
+		select {
+		case <-s.release:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return s.MemoryStore.Save(ctx, key, entry)
+}
+
+func TestWriteThroughStoreDoesNotBlockShard(t *testing.T) {
+	t.Parallel()
+
+	// given
+	store := &blockingStore{MemoryStore: NewMemoryStore(), saving: make(chan struct{}, 1), release: make(chan struct{})}
+	cache, _ := New(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntriesSize:     256,
+		Store:              store,
+	})
+	defer cache.Close()
+	noError(t, cache.Set("slow", []byte("old")))
+
+	// when the Store is still saving a key
+	done := make(chan error, 1)
+	go func() {
+		done <- cache.Set("slow", []byte("blocked"))
+	}()
+	<-store.saving
+
+	// then the shard serves reads and other writes meanwhile
+	value, err := cache.Get("slow")
+	noError(t, err)
+	assertEqual(t, []byte("old"), value)
+	noError(t, cache.Set("fast", []byte("other")))
+	value, err = cache.Get("fast")
+	noError(t, err)
+	assertEqual(t, []byte("other"), value)
+
+	// when
+	close(store.release)
+
+	// then
+	noError(t, <-done)
+	value, err = cache.Get("slow")
+	noError(t, err)
+	assertEqual(t, []byte("blocked"), value)
+}
+
+func TestWriteThroughStoreTimeout(t *testing.T) {
+	t.Parallel()
+
+	// given
+	store := &blockingStore{MemoryStore: NewMemoryStore(), saving: make(chan struct{}, 1), release: make(chan struct{})}
+	cache, _ := New(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntriesSize:     256,
+		Store:              store,
+		StoreTimeout:       10 * time.Millisecond,
+	})
+	defer cache.Close()
+
+	// when
+	err := cache.Set("slow", []byte("blocked"))
+	_, getErr := cache.Get("slow")
+
+	// then
+	assertEqual(t, context.DeadlineExceeded, err)
+	assertEqual(t, ErrEntryNotFound, getErr)
+}
+
+func TestWriteBehindStoreFlushOnClose(t *testing.T) {
+	t.Parallel()
+
+	// given
+	store := NewMemoryStore()
+	config := Config{
+		Shards:             8,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1000,
+		MaxEntriesSize:     256,
+	}
+	config.Store = store
+	config.StoreMode = WriteBehind
+	config.WriteBehindInterval = time.Hour
+	cache, _ := New(context.Background(), config)
+	defer cache.Close()
+
+	// when
+	noError(t, cache.Set("key", []byte("first")))
+	noError(t, cache.Set("key", []byte("second")))
+	noError(t, cache.Set("other", []byte("value")))
+	noError(t, cache.Delete("other"))
+	_, err := store.Load(context.Background(), "key")
+
+	// then
+	assertEqual(t, ErrKnownAbsent, err)
+
+	// when
+	noError(t, cache.Close())
+	stored, err := store.Load(context.Background(), "key")
+	_, otherErr := store.Load(context.Background(), "other")
+
+	// then
+	noError(t, err)
+	assertEqual(t, []byte("second"), stored)
+	assertEqual(t, ErrKnownAbsent, otherErr)
+}
+
+func TestWriteBehindStoreRefusesWritesAfterStop(t *testing.T) {
+	t.Parallel()
+
+	// given
+	store := NewMemoryStore()
+	ctx, cancel := context.WithCancel(context.Background())
+	closed, _ := New(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntriesSize:     256,
+		Store:              store,
+		StoreMode:          WriteBehind,
+	})
+	cancelled, _ := New(ctx, Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntriesSize:     256,
+		Store:              store,
+		StoreMode:          WriteBehind,
+	})
+
+	// when
+	noError(t, closed.Close())
+	cancel()
+	<-cancelled.writeBehind.done
+
+	// then
+	assertEqual(t, ErrStoreClosed, closed.Set("key", []byte("value")))
+	assertEqual(t, ErrStoreClosed, cancelled.Delete("key"))
+	_, err := closed.Get("key")
+	assertEqual(t, ErrEntryNotFound, err)
+}
+
+func TestWriteBehindStoreBatchSize(t *testing.T) {
+	t.Parallel()
+
+	// given
+	store := NewMemoryStore()
+	config := Config{
+		Shards:             8,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1000,
+		MaxEntriesSize:     256,
+	}
+	config.Store = store
+	config.StoreMode = WriteBehind
+	config.WriteBehindInterval = time.Hour
+	config.WriteBehindBatchSize = 2
+	cache, _ := New(context.Background(), config)
+	defer cache.Close()
+
+	// when
+	noError(t, cache.Set("a", []byte("1")))
+	noError(t, cache.Set("b", []byte("2")))
+
+	// then
+	for i := 0; i < 100; i++ {
+		if _, err := store.Load(context.Background(), "b"); err == nil {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Error("batch was not flushed")
+}
+
+func TestWriteBehindStoreRetries(t *testing.T) {
+	t.Parallel()
+
+	// given
+	store := &failingStore{MemoryStore: NewMemoryStore(), failures: 2}
+	var failed []string
+	config := Config{
+		Shards:             8,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1000,
+		MaxEntriesSize:     256,
+	}
+	config.Store = store
+	config.StoreMode = WriteBehind
+	config.WriteBehindInterval = 10 * time.Millisecond
+	config.WriteBehindRetries = 3
+	config.OnStoreError = func(key string, err error) {
+		failed = append(failed, key)
+	}
+	cache, _ := New(context.Background(), config)
+	defer cache.Close()
+
+	// when
+	noError(t, cache.Set("key", []byte("value")))
+	time.Sleep(100 * time.Millisecond)
+	noError(t, cache.Close())
+	stored, err := store.Load(context.Background(), "key")
+
+	// then
+	noError(t, err)
+	assertEqual(t, []byte("value"), stored)
+	assertEqual(t, 0, len(failed))
+}
+
+func TestWriteBehindStoreErrorCallback(t *testing.T) {
+	t.Parallel()
+
+	// given
+	store := &failingStore{MemoryStore: NewMemoryStore(), failures: 10}
+	var failed []string
+	config := Config{
+		Shards:             8,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1000,
+		MaxEntriesSize:     256,
+	}
+	config.Store = store
+	config.StoreMode = WriteBehind
+	config.WriteBehindInterval = time.Hour
+	config.WriteBehindRetries = 1
+	config.OnStoreError = func(key string, err error) {
+		failed = append(failed, key)
+	}
+	cache, _ := New(context.Background(), config)
+	defer cache.Close()
+
+	// when
+	noError(t, cache.Set("key", []byte("value")))
+	err := cache.Close()
+
+	// then
+	assertEqual(t, "store unavailable", err.Error())
+	assertEqual(t, []string{"key"}, failed)
+}
+
+func TestGetThrough(t *testing.T) {
+	t.Parallel()
+
+	// given
+	store := NewMemoryStore()
+	noError(t, store.Save(context.Background(), "key", []byte("value")))
+	config := Config{
+		Shards:             8,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1000,
+		MaxEntriesSize:     256,
+	}
+	config.Store = store
+	cache, _ := New(context.Background(), config)
+	defer cache.Close()
+
+	// when
+	value, err := cache.GetThrough(context.Background(), "key")
+	cached, cachedErr := cache.Get("key")
+	_, missingErr := cache.GetThrough(context.Background(), "missing")
+
+	// then
+	noError(t, err)
+	noError(t, cachedErr)
+	assertEqual(t, []byte("value"), value)
+	assertEqual(t, []byte("value"), cached)
+	assertEqual(t, ErrKnownAbsent, missingErr)
+}
+
+func TestStoreReceivesEveryWrite(t *testing.T) {
+	t.Parallel()
+
+	// given
+	store := NewMemoryStore()
+	cache, _ := New(context.Background(), Config{
+		Shards:             4,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 100,
+		MaxEntriesSize:     256,
+		Store:              store,
+	})
+	defer cache.Close()
+	stored := func(key string) string {
+		entry, err := store.Load(context.Background(), key)
+		if err != nil {
+			return err.Error()
+		}
+		return string(entry)
+	}
+	absent := ErrKnownAbsent.Error()
+
+	// when
+	cache.SetIfAbsent("added", []byte("1"))
+	cache.Replace("added", []byte("2"))
+	cache.GetAndSet("swapped", []byte("3"))
+	_, version, _ := cache.GetWithVersion("swapped")
+	cache.CompareAndSwap("swapped", version, []byte("4"))
+	cache.IncrBy("counter", 5)
+	cache.Incr("counter")
+	cache.Append("appended", []byte("a"))
+	cache.Append("appended", []byte("b"))
+	cache.Set("taken", []byte("x"))
+	cache.GetAndDelete("taken")
+	cache.Set("absent", []byte("x"))
+	cache.SetAbsent("absent", 2*time.Second)
+	cache.Set("prefix-1", []byte("x"))
+	cache.DeletePrefix("prefix-")
+	cache.SetWithTags("tagged", []byte("x"), "tag")
+	cache.InvalidateTag("tag")
+
+	// then
+	assertEqual(t, "2", stored("added"))
+	assertEqual(t, "4", stored("swapped"))
+	assertEqual(t, "6", stored("counter"))
+	assertEqual(t, "ab", stored("appended"))
+	assertEqual(t, absent, stored("taken"))
+	assertEqual(t, "x", stored("absent"))
+	assertEqual(t, absent, stored("prefix-1"))
+	assertEqual(t, absent, stored("tagged"))
+	assertEqual(t, ErrNamespaceWithStore, cache.Namespace("ns").Set("key", []byte("x")))
+	assertEqual(t, ErrNamespaceWithStore, cache.Namespace("ns").Delete("key"))
+}
@@ -0,0 +1,101 @@
+package largecache
+
+func (s *cacheShard) setWithTags(key string, hashedKey uint64, entry []byte, tags []string) error {
+	currentTimestamp := uint64(s.clock.Epoch())
+
+	s.lock.Lock()
+	err := s.setWithoutLock(currentTimestamp, defaultNamespace, key, hashedKey, entry, 0)
+	if err == nil && len(tags) > 0 {
+		if index, _ := s.findWithoutLock(defaultNamespace, key, hashedKey); index != 0 {
+			if wrappedEntry, err := s.entries.Get(int(index)); err == nil {
+				markEntryTagged(wrappedEntry)
+				s.tagWithoutLock(collisionKey{namespace: defaultNamespace, key: key}, hashedKey, tags)
+			}
+		}
+	}
+	s.lock.Unlock()
+
+	return err
+}
+
+func (s *cacheShard) invalidateTag(tag string) int {
+	s.lock.Lock()
+	removed := 0
+	for ck, hashedKey := range s.tags[tag] {
+		if s.invalidateKeyWithoutLock(ck, hashedKey) {
+			removed++
+		}
+	}
+	s.lock.Unlock()
+
+	return removed
+}
+
+// taggedKeys returns references to the entries carrying the tag.
+func (s *cacheShard) taggedKeys(tag string) []entryRef {
+	s.lock.RLock()
+	refs := make([]entryRef, 0, len(s.tags[tag]))
+	for ck, hashedKey := range s.tags[tag] {
+		refs = append(refs, entryRef{hashedKey: hashedKey, key: ck})
+	}
+	s.lock.RUnlock()
+	return refs
+}
+
+func (s *cacheShard) isTagged(tag string, ref entryRef) bool {
+	s.lock.RLock()
+	_, ok := s.tags[tag][ref.key]
+	s.lock.RUnlock()
+	return ok
+}
+
+// invalidateKey removes the entry of the reference if it still carries the tag.
+func (s *cacheShard) invalidateKey(tag string, ref entryRef) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, ok := s.tags[tag][ref.key]; !ok {
+		return false
+	}
+	return s.invalidateKeyWithoutLock(ref.key, ref.hashedKey)
+}
+
+func (s *cacheShard) invalidateKeyWithoutLock(ck collisionKey, hashedKey uint64) bool {
+	index, _ := s.findWithoutLock(ck.namespace, ck.key, hashedKey)
+	wrappedEntry, err := s.entries.Get(int(index))
+	if index == 0 || err != nil {
+		s.untagKeyWithoutLock(ck)
+		return false
+	}
+	s.removeWithoutLock(wrappedEntry, hashedKey, Invalidated)
+	return true
+}
+
+// tagWithoutLock indexes the tags by key rather than by hash, so entries of colliding keys keep their own tags.
+func (s *cacheShard) tagWithoutLock(ck collisionKey, hashedKey uint64, tags []string) {
+	for _, tag := range tags {
+		keys, ok := s.tags[tag]
+		if !ok {
+			keys = make(map[collisionKey]uint64)
+			s.tags[tag] = keys
+		}
+		keys[ck] = hashedKey
+	}
+	s.entryTags[ck] = append([]string(nil), tags...)
+}
+
+// untagWithoutLock drops the key of the entry from the tag index, it is called whenever a tagged entry
+// leaves the shard so the index never points at removed or replaced entries.
+func (s *cacheShard) untagWithoutLock(wrappedEntry []byte) {
+	s.untagKeyWithoutLock(entryCollisionKey(wrappedEntry))
+}
+
+func (s *cacheShard) untagKeyWithoutLock(ck collisionKey) {
+	for _, tag := range s.entryTags[ck] {
+		keys := s.tags[tag]
+		delete(keys, ck)
+		if len(keys) == 0 {
+			delete(s.tags, tag)
+		}
+	}
+	delete(s.entryTags, ck)
+}
@@ -0,0 +1,136 @@
+package largecache
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestInvalidateTag(t *testing.T) {
+	t.Parallel()
+
+	// given
+	var removed []string
+	var reasons []RemoveReason
+	config := Config{
+		Shards:             8,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1000,
+		MaxEntriesSize:     256,
+	}
+	config.OnRemoveWithReason = func(key string, entry []byte, reason RemoveReason) {
+		removed = append(removed, key)
+		reasons = append(reasons, reason)
+	}
+	cache, _ := New(context.Background(), config)
+	defer cache.Close()
+	noError(t, cache.SetWithTags("page:1", []byte("a"), "product:42", "product:7"))
+	noError(t, cache.SetWithTags("page:2", []byte("b"), "product:42"))
+	noError(t, cache.SetWithTags("page:3", []byte("c"), "product:7"))
+	noError(t, cache.Set("page:4", []byte("d")))
+
+	// when
+	count, err := cache.InvalidateTag("product:42")
+
+	// then
+	noError(t, err)
+	assertEqual(t, 2, count)
+	sort.Strings(removed)
+	assertEqual(t, []string{"page:1", "page:2"}, removed)
+	assertEqual(t, []RemoveReason{Invalidated, Invalidated}, reasons)
+	_, err = cache.Get("page:1")
+	assertEqual(t, ErrEntryNotFound, err)
+	_, err = cache.Get("page:3")
+	noError(t, err)
+
+	// when
+	count, _ = cache.InvalidateTag("product:7")
+
+	// then
+	assertEqual(t, 1, count)
+	_, err = cache.Get("page:4")
+	noError(t, err)
+}
+
+func TestSetDropsTags(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := New(context.Background(), Config{
+		Shards:             8,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1000,
+		MaxEntriesSize:     256,
+	})
+	defer cache.Close()
+	noError(t, cache.SetWithTags("key", []byte("tagged"), "tag"))
+
+	// when
+	noError(t, cache.Set("key", []byte("untagged")))
+	count, _ := cache.InvalidateTag("tag")
+
+	// then
+	assertEqual(t, 0, count)
+	value, err := cache.Get("key")
+	noError(t, err)
+	assertEqual(t, []byte("untagged"), value)
+}
+
+func TestTagIndexFollowsRemovals(t *testing.T) {
+	t.Parallel()
+
+	// given
+	clock := mockedClock{value: 0}
+	cache, _ := newLargeCache(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         10 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntriesSize:     256,
+	}, &clock)
+	defer cache.Close()
+	shard := cache.shards[0]
+	noError(t, cache.SetWithTags("expired", []byte("a"), "tag"))
+	noError(t, cache.SetWithTags("deleted", []byte("b"), "tag"))
+	clock.set(5)
+	noError(t, cache.SetWithTags("touched", []byte("c"), "tag"))
+
+	// when
+	noError(t, cache.Delete("deleted"))
+	clock.set(12)
+	noError(t, cache.Touch("touched"))
+	cache.cleanUp(uint64(clock.Epoch()))
+
+	// then
+	assertEqual(t, 1, len(shard.tags["tag"]))
+	assertEqual(t, 1, len(shard.entryTags))
+	count, _ := cache.InvalidateTag("tag")
+	assertEqual(t, 1, count)
+	assertEqual(t, 0, len(shard.tags))
+	assertEqual(t, 0, len(shard.entryTags))
+}
+
+func TestTagIndexFollowsEviction(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := New(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         time.Minute,
+		MaxEntriesInWindow: 1,
+		MaxEntriesSize:     1,
+		HardMaxCacheSize:   1,
+	})
+	defer cache.Close()
+	shard := cache.shards[0]
+	value := make([]byte, 200*1024)
+
+	// when
+	for i := 0; i < 20; i++ {
+		noError(t, cache.SetWithTags(string(rune('a'+i)), value, "tag"))
+	}
+
+	// then
+	assertEqual(t, cache.Len(), len(shard.entryTags))
+	assertEqual(t, cache.Len(), len(shard.tags["tag"]))
+}
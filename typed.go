@@ -0,0 +1,106 @@
+package largecache
+
+import "fmt"
+
+// DecodeError is returned by Typed when an entry is present but cannot be decoded by its Codec.
+type DecodeError struct {
+	Key string
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("cannot decode entry %q: %s", e.Key, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// Typed stores values of type V in LargeCache using codec to convert them to entries.
+type Typed[V any] struct {
+	cache *LargeCache
+	codec Codec[V]
+}
+
+func NewTyped[V any](cache *LargeCache, codec Codec[V]) *Typed[V] {
+	return &Typed[V]{
+		cache: cache,
+		codec: codec,
+	}
+}
+
+// Get reads the value for the key. It returns ErrEntryNotFound for a missing key
+// and *DecodeError when the entry cannot be decoded.
+func (t *Typed[V]) Get(key string) (V, error) {
+	var value V
+	entry, err := t.cache.Get(key)
+	if err != nil {
+		return value, err
+	}
+	return t.decode(key, entry)
+}
+
+func (t *Typed[V]) Set(key string, value V) error {
+	entry, err := t.codec.Encode(value)
+	if err != nil {
+		return err
+	}
+	return t.cache.Set(key, entry)
+}
+
+func (t *Typed[V]) Delete(key string) error {
+	return t.cache.Delete(key)
+}
+
+func (t *Typed[V]) Iterator() *TypedIterator[V] {
+	return &TypedIterator[V]{
+		iterator: t.cache.Iterator(),
+		codec:    t.codec,
+	}
+}
+
+func (t *Typed[V]) decode(key string, entry []byte) (V, error) {
+	value, err := t.codec.Decode(entry)
+	if err != nil {
+		return value, &DecodeError{Key: key, Err: err}
+	}
+	return value, nil
+}
+
+// TypedIterator walks over the entries of the cache decoding them with the codec of Typed.
+type TypedIterator[V any] struct {
+	iterator *EntryInfoIterator
+	codec    Codec[V]
+}
+
+func (it *TypedIterator[V]) SetNext() bool {
+	return it.iterator.SetNext()
+}
+
+// Value returns the current key with its decoded value, a *DecodeError is returned
+// for entries which cannot be decoded.
+func (it *TypedIterator[V]) Value() (string, V, error) {
+	var value V
+	entry, err := it.iterator.Value()
+	if err != nil {
+		return "", value, err
+	}
+
+	value, err = it.codec.Decode(entry.Value())
+	if err != nil {
+		return entry.Key(), value, &DecodeError{Key: entry.Key(), Err: err}
+	}
+	return entry.Key(), value, nil
+}
+
+// TypedOnRemoveWithReason adapts fn to be used as Config.OnRemoveWithReason of a cache storing
+// values encoded with codec. A *DecodeError is passed to fn when the entry cannot be decoded.
+func TypedOnRemoveWithReason[V any](codec Codec[V], fn func(key string, value V, reason RemoveReason, err error)) func(key string, entry []byte, reason RemoveReason) {
+	return func(key string, entry []byte, reason RemoveReason) {
+		value, err := codec.Decode(entry)
+		if err != nil {
+			err = &DecodeError{Key: key, Err: err}
+		}
+		fn(key, value, reason, err)
+	}
+}
@@ -0,0 +1,135 @@
+package largecache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type typedTestValue struct {
+	Name  string
+	Count int
+}
+
+func TestTypedGetSet(t *testing.T) {
+	t.Parallel()
+
+	for name, codec := range map[string]Codec[typedTestValue]{
+		"json": JSONCodec[typedTestValue]{},
+		"gob":  GobCodec[typedTestValue]{},
+	} {
+		codec := codec
+		t.Run(name, func(t *testing.T) {
+			cache, _ := New(context.Background(), Config{
+				Shards:             8,
+				LifeWindow:         5 * time.Second,
+				MaxEntriesInWindow: 1000,
+				MaxEntriesSize:     256,
+			})
+			defer cache.Close()
+			typed := NewTyped[typedTestValue](cache, codec)
+
+			_, err := typed.Get("key")
+			assertEqual(t, ErrEntryNotFound, err)
+
+			noError(t, typed.Set("key", typedTestValue{Name: "name", Count: 42}))
+			value, err := typed.Get("key")
+			noError(t, err)
+			assertEqual(t, typedTestValue{Name: "name", Count: 42}, value)
+		})
+	}
+}
+
+func TestTypedDecodeError(t *testing.T) {
+	t.Parallel()
+
+	cache, _ := New(context.Background(), Config{
+		Shards:             8,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1000,
+		MaxEntriesSize:     256,
+	})
+	defer cache.Close()
+	typed := NewTyped[typedTestValue](cache, JSONCodec[typedTestValue]{})
+	cache.Set("key", []byte("not json"))
+
+	_, err := typed.Get("key")
+
+	var decodeErr *DecodeError
+	assertEqual(t, true, errors.As(err, &decodeErr))
+	assertEqual(t, "key", decodeErr.Key)
+	assertEqual(t, false, errors.Is(err, ErrEntryNotFound))
+}
+
+func TestTypedStringAndBytesCodec(t *testing.T) {
+	t.Parallel()
+
+	cache, _ := New(context.Background(), Config{
+		Shards:             8,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1000,
+		MaxEntriesSize:     256,
+	})
+	defer cache.Close()
+	strings := NewTyped[string](cache, StringCodec{})
+	raw := NewTyped[[]byte](cache, BytesCodec{})
+
+	noError(t, strings.Set("key", "value"))
+	value, err := raw.Get("key")
+	noError(t, err)
+	assertEqual(t, []byte("value"), value)
+
+	noError(t, raw.Set("key", []byte("value2")))
+	str, err := strings.Get("key")
+	noError(t, err)
+	assertEqual(t, "value2", str)
+}
+
+func TestTypedIterator(t *testing.T) {
+	t.Parallel()
+
+	cache, _ := New(context.Background(), Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntriesSize:     256,
+	})
+	defer cache.Close()
+	typed := NewTyped[typedTestValue](cache, JSONCodec[typedTestValue]{})
+	typed.Set("key", typedTestValue{Name: "name"})
+
+	iterator := typed.Iterator()
+	assertEqual(t, true, iterator.SetNext())
+	key, value, err := iterator.Value()
+	noError(t, err)
+	assertEqual(t, "key", key)
+	assertEqual(t, typedTestValue{Name: "name"}, value)
+	assertEqual(t, false, iterator.SetNext())
+}
+
+func TestTypedOnRemoveWithReason(t *testing.T) {
+	t.Parallel()
+
+	codec := JSONCodec[typedTestValue]{}
+	var removed typedTestValue
+	conf := Config{
+		Shards:             8,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1000,
+		MaxEntriesSize:     256,
+	}
+	conf.OnRemoveWithReason = TypedOnRemoveWithReason[typedTestValue](codec, func(key string, value typedTestValue, reason RemoveReason, err error) {
+		noError(t, err)
+		assertEqual(t, Deleted, reason)
+		removed = value
+	})
+	cache, _ := New(context.Background(), conf)
+	defer cache.Close()
+	typed := NewTyped[typedTestValue](cache, codec)
+
+	typed.Set("key", typedTestValue{Name: "name"})
+	noError(t, typed.Delete("key"))
+
+	assertEqual(t, typedTestValue{Name: "name"}, removed)
+}
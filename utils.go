@@ -1,5 +1,7 @@
 package largecache
 
+import "time"
+
 func max(a, b int) int {
 	if a > b {
 		return a
@@ -14,3 +16,11 @@ func convertMBToBytes(value int) int {
 func isPowerOfTwo(number int) bool {
 	return (number != 0) && (number&(number-1)) == 0
 }
+
+// expiryFromTime converts t to the entry expiry, 0 is reserved for entries without one.
+func expiryFromTime(t time.Time) uint64 {
+	if t.Unix() <= 0 {
+		return 1
+	}
+	return uint64(t.Unix())
+}